@@ -0,0 +1,191 @@
+package main
+
+import (
+	"./engine"
+	"./input"
+	"bytes"
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+	"os"
+	"strings"
+)
+
+const bindingsFile = "bindings.txt"
+const menuX = 400
+
+func defaultBindings() string {
+	return `
+yaw = axis:leftx*-1 mouse:lookx*-1
+pitch = axis:lefty mouse:looky
+roll = pad:LB key:Q pad:RB*-1 key:E*-1
+thrust_right = axis:rightx key:D key:A*-1
+thrust_up = axis:righty key:W key:S*-1
+thrust_forward = axis:rt axis:lt*-1 key:LeftShift key:LeftControl*-1
+reset = key:R pad:A
+mode = key:F pad:Y
+pause = key:Space pad:B
+debug = key:F3
+escape = key:Escape
+quit = pad:Select
+mouse_mode = key:M
+fullscreen = key:Enter
+`
+}
+
+// Mouse settings for flying by relative movement, where mouseScale makes a
+// pixel of movement match the stick, and by virtual joystick.
+var relativeMouse = input.MouseSettings{Mode: input.MouseRelative, Sensitivity: mouseScale, Smoothing: 0.03}
+var joystickMouse = input.MouseSettings{Mode: input.MouseJoystick, Sensitivity: 1, Radius: 250,
+	Deadzone: input.StickDeadzone{Shape: input.DeadzoneScaledRadial,
+		Deadzone: input.Deadzone{Inner: 0.08, Outer: 1, Exponent: 1.5}},
+	Smoothing: 0.05}
+
+// toggleMouseMode switches between flying by relative mouse movement and by
+// virtual joystick.
+func toggleMouseMode(engine *engine.Engine) {
+	if engine.MouseSettings().Mode == input.MouseJoystick {
+		engine.SetMouseSettings(relativeMouse)
+	} else {
+		engine.SetMouseSettings(joystickMouse)
+	}
+}
+
+// drawReticle marks the center of the virtual joystick and where it is
+// pushed, while it is in use.
+func drawReticle(engine *engine.Engine, mouse *input.Mouse) {
+	if mouse.Settings.Mode != input.MouseJoystick || !engine.IsMouseGrabbed() {
+		return
+	}
+	width, height := engine.Viewport()
+	fnt := engine.Font("hud")
+	center := mgl32.Vec2{float32(width) / 2, float32(height) / 2}
+	// Screen y runs down, stick y up.
+	pos := center.Add(mgl32.Vec2{mouse.Stick.X(), -mouse.Stick.Y()}.Mul(reticleRadius))
+	for _, mark := range []struct {
+		text  string
+		at    mgl32.Vec2
+		color mgl32.Vec4
+	}{{"o", center, mgl32.Vec4{1, 1, 1, 0.3}}, {"+", pos, mgl32.Vec4{1, 1, 0.6, 0.8}}} {
+		w, h := fnt.TextSize(mark.text, hudScale)
+		if err := engine.DrawText("hud", mark.text, mark.at.X()-w/2, mark.at.Y()-h/2, hudScale, mark.color); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// How far in pixels the reticle moves at full deflection.
+const reticleRadius = 120
+
+// controls holds the action bindings and the menu that rebinds them. F1
+// opens the menu; Up and Down pick an action, Enter rebinds it to the next
+// input, Backspace clears it and Delete restores the defaults.
+type controls struct {
+	actions   *input.Actions
+	menu      bool
+	selected  int
+	capturing bool
+	// Whether everything has been released since capturing started, so the
+	// key that started it is not captured.
+	armed bool
+}
+
+func (this *controls) init() {
+	this.actions = input.NewActions()
+	if err := this.actions.Parse(strings.NewReader(defaultBindings())); err != nil {
+		panic(err)
+	}
+	if err := this.actions.Load(bindingsFile); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Could not load %s: %v\n", bindingsFile, err)
+	}
+}
+func (this *controls) save() {
+	if err := this.actions.Save(bindingsFile); err != nil {
+		fmt.Printf("Could not save %s: %v\n", bindingsFile, err)
+	}
+}
+
+// tick updates the actions and runs the menu.
+func (this *controls) tick(engine *engine.Engine, in *input.Input) {
+	this.actions.Update(in, engine.GetKey)
+	if engine.GetKeyPressed(glfw.KeyF1) {
+		this.menu = !this.menu
+		this.capturing = false
+	}
+	if !this.menu {
+		return
+	}
+	names := this.actions.Names()
+	if this.capturing {
+		source, ok := input.Capture(in, this.actions.Pad, engine.GetKey)
+		if !ok {
+			this.armed = true
+		} else if this.armed {
+			this.capturing = false
+			if source.Kind != input.SourceKey || glfw.Key(source.Code) != glfw.KeyEscape {
+				this.actions.Rebind(names[this.selected], source)
+				this.save()
+			}
+		}
+		return
+	}
+	if engine.GetKeyPressed(glfw.KeyUp) && this.selected > 0 {
+		this.selected--
+	}
+	if engine.GetKeyPressed(glfw.KeyDown) && this.selected < len(names)-1 {
+		this.selected++
+	}
+	if engine.GetKeyPressed(glfw.KeyEnter) {
+		this.capturing = true
+		this.armed = false
+	}
+	if engine.GetKeyPressed(glfw.KeyBackspace) {
+		this.actions.Unbind(names[this.selected])
+		this.save()
+	}
+	if engine.GetKeyPressed(glfw.KeyDelete) {
+		this.actions = input.NewActions()
+		if err := this.actions.Parse(strings.NewReader(defaultBindings())); err != nil {
+			panic(err)
+		}
+		this.clampSelected()
+		this.save()
+	}
+}
+
+// clampSelected keeps the menu on an action after the actions change.
+func (this *controls) clampSelected() {
+	if last := len(this.actions.Names()) - 1; this.selected > last {
+		this.selected = last
+	}
+	if this.selected < 0 {
+		this.selected = 0
+	}
+}
+
+func (this *controls) draw(engine *engine.Engine) {
+	if !this.menu {
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString("Controls (F1 to close)\n\n")
+	for i, name := range this.actions.Names() {
+		cursor := "  "
+		if i == this.selected {
+			cursor = "> "
+		}
+		buf.WriteString(cursor + name + " =")
+		for _, source := range this.actions.Bindings(name) {
+			buf.WriteString(" " + source.String())
+		}
+		buf.WriteString("\n")
+	}
+	if this.capturing {
+		buf.WriteString("\nPress the new input, or Escape to cancel")
+	} else {
+		buf.WriteString("\nEnter: rebind  Backspace: clear  Delete: defaults")
+	}
+	if err := engine.DrawText("hud", buf.String(), menuX, hudMargin, 1, mgl32.Vec4{1, 1, 0.6, 0.9}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+)
+
+// A blockBuffer backs a uniform or shader storage block. Sizes and offsets
+// are in floats.
+type blockBuffer struct {
+	id      uint32
+	target  uint32
+	binding uint32
+	size    int
+}
+
+// BindUniformBlock connects the uniform block called block in program to a
+// binding point, where SetUniformBuffer attaches its buffers.
+func (this *Engine) BindUniformBlock(program, block string, binding uint32) error {
+	prog, ok := this.programs[program]
+	if !ok {
+		return fmt.Errorf("no program %s", program)
+	}
+	index := gl.GetUniformBlockIndex(prog, gl.Str(block+"\x00"))
+	if index == gl.INVALID_INDEX {
+		return fmt.Errorf("program %s has no active uniform block %s", program, block)
+	}
+	gl.UniformBlockBinding(prog, index, binding)
+	return nil
+}
+
+// BindStorageBlock connects the shader storage block called block in program
+// to a binding point, where SetStorageBuffer attaches its buffers.
+func (this *Engine) BindStorageBlock(program, block string, binding uint32) error {
+	prog, ok := this.programs[program]
+	if !ok {
+		return fmt.Errorf("no program %s", program)
+	}
+	index := gl.GetProgramResourceIndex(prog, gl.SHADER_STORAGE_BLOCK, gl.Str(block+"\x00"))
+	if index == gl.INVALID_INDEX {
+		return fmt.Errorf("program %s has no active storage block %s", program, block)
+	}
+	gl.ShaderStorageBlockBinding(prog, index, binding)
+	return nil
+}
+
+func (this *Engine) setBlockBuffer(target uint32, name string, binding uint32, data []float32) {
+	buf, ok := this.blockBuffers[name]
+	if !ok {
+		buf = &blockBuffer{target: target}
+		gl.GenBuffers(1, &buf.id)
+		this.blockBuffers[name] = buf
+	}
+	gl.BindBuffer(target, buf.id)
+	if buf.target != target || buf.size != len(data) {
+		if len(data) == 0 {
+			gl.BufferData(target, 0, nil, gl.DYNAMIC_DRAW)
+		} else {
+			gl.BufferData(target, len(data)*4, gl.Ptr(data), gl.DYNAMIC_DRAW)
+		}
+	} else if len(data) != 0 {
+		gl.BufferSubData(target, 0, len(data)*4, gl.Ptr(data))
+	}
+	buf.target, buf.binding, buf.size = target, binding, len(data)
+	gl.BindBufferBase(target, binding, buf.id)
+}
+func (this *Engine) updateBlockBuffer(target uint32, name string, offset int, data []float32) error {
+	buf, ok := this.blockBuffers[name]
+	if !ok || buf.target != target {
+		return fmt.Errorf("no block buffer %s of that kind", name)
+	}
+	if offset < 0 || offset+len(data) > buf.size {
+		return fmt.Errorf("update of %d floats at %d overruns block buffer %s of %d floats",
+			len(data), offset, name, buf.size)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	gl.BindBuffer(target, buf.id)
+	gl.BufferSubData(target, offset*4, len(data)*4, gl.Ptr(data))
+	return nil
+}
+
+// SetUniformBuffer uploads data to the uniform buffer called name and attaches
+// it to binding, creating the buffer on first use. Data must follow the std140
+// layout of the block it feeds.
+func (this *Engine) SetUniformBuffer(name string, binding uint32, data []float32) {
+	this.setBlockBuffer(gl.UNIFORM_BUFFER, name, binding, data)
+}
+
+// UpdateUniformBuffer overwrites part of the uniform buffer called name,
+// starting offset floats in.
+func (this *Engine) UpdateUniformBuffer(name string, offset int, data []float32) error {
+	return this.updateBlockBuffer(gl.UNIFORM_BUFFER, name, offset, data)
+}
+
+// SetStorageBuffer uploads data to the shader storage buffer called name and
+// attaches it to binding, creating the buffer on first use.
+func (this *Engine) SetStorageBuffer(name string, binding uint32, data []float32) {
+	this.setBlockBuffer(gl.SHADER_STORAGE_BUFFER, name, binding, data)
+}
+
+// UpdateStorageBuffer overwrites part of the shader storage buffer called
+// name, starting offset floats in.
+func (this *Engine) UpdateStorageBuffer(name string, offset int, data []float32) error {
+	return this.updateBlockBuffer(gl.SHADER_STORAGE_BUFFER, name, offset, data)
+}
+
+// AttachBlockBuffer attaches the existing block buffer called name to its
+// binding point again, after another buffer has taken it.
+func (this *Engine) AttachBlockBuffer(name string) error {
+	buf, ok := this.blockBuffers[name]
+	if !ok {
+		return fmt.Errorf("no block buffer %s", name)
+	}
+	gl.BindBufferBase(buf.target, buf.binding, buf.id)
+	return nil
+}
+
+// ReadStorageBuffer copies the start of the shader storage buffer called name
+// back into data.
+func (this *Engine) ReadStorageBuffer(name string, data []float32) error {
+	buf, ok := this.blockBuffers[name]
+	if !ok || buf.target != gl.SHADER_STORAGE_BUFFER {
+		return fmt.Errorf("no storage buffer %s", name)
+	}
+	if len(data) > buf.size {
+		return fmt.Errorf("storage buffer %s holds %d floats, not %d", name, buf.size, len(data))
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, buf.id)
+	gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, 0, len(data)*4, gl.Ptr(data))
+	return nil
+}
@@ -0,0 +1,245 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+	"sort"
+)
+
+// A buffer is a vertex buffer of floats. It remembers its usage hint, the
+// size it was last allocated with and which ranges of its client-side copy
+// changed since the last upload. Sizes, offsets and ranges are in floats.
+type buffer struct {
+	id    uint32
+	usage uint32
+	size  int
+	dirty [][2]int
+}
+
+func newBuffer() *buffer {
+	ans := &buffer{usage: gl.STATIC_DRAW, size: -1}
+	gl.GenBuffers(1, &ans.id)
+	return ans
+}
+
+// set uploads all of data, reallocating the buffer if its size changed.
+func (this *buffer) set(data []float32) {
+	gl.BindBuffer(gl.ARRAY_BUFFER, this.id)
+	if len(data) != this.size {
+		if len(data) == 0 {
+			gl.BufferData(gl.ARRAY_BUFFER, 0, nil, this.usage)
+		} else {
+			gl.BufferData(gl.ARRAY_BUFFER, len(data)*4, gl.Ptr(data), this.usage)
+		}
+		this.size = len(data)
+	} else if len(data) != 0 {
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(data)*4, gl.Ptr(data))
+	}
+	this.dirty = this.dirty[:0]
+}
+func (this *buffer) update(offset int, data []float32) error {
+	if offset < 0 || offset+len(data) > this.size {
+		return fmt.Errorf("update of %d floats at %d overruns buffer of %d floats", len(data), offset, this.size)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, this.id)
+	gl.BufferSubData(gl.ARRAY_BUFFER, offset*4, len(data)*4, gl.Ptr(data))
+	return nil
+}
+func (this *buffer) markDirty(start, end int) {
+	if end > start {
+		this.dirty = append(this.dirty, [2]int{start, end})
+	}
+}
+
+// flush uploads the dirty ranges of data, merged where they touch, or all of
+// data if the buffer has never held data of this size.
+func (this *buffer) flush(data []float32) {
+	if len(data) != this.size {
+		this.set(data)
+		return
+	}
+	if len(this.dirty) == 0 {
+		return
+	}
+	sort.Slice(this.dirty, func(i, j int) bool { return this.dirty[i][0] < this.dirty[j][0] })
+	gl.BindBuffer(gl.ARRAY_BUFFER, this.id)
+	cur := this.dirty[0]
+	for i := 1; i <= len(this.dirty); i++ {
+		if i < len(this.dirty) && this.dirty[i][0] <= cur[1] {
+			if this.dirty[i][1] > cur[1] {
+				cur[1] = this.dirty[i][1]
+			}
+			continue
+		}
+		if cur[0] < 0 {
+			cur[0] = 0
+		}
+		if cur[1] > len(data) {
+			cur[1] = len(data)
+		}
+		if cur[1] > cur[0] {
+			gl.BufferSubData(gl.ARRAY_BUFFER, cur[0]*4, (cur[1]-cur[0])*4, gl.Ptr(data[cur[0]:cur[1]]))
+		}
+		if i < len(this.dirty) {
+			cur = this.dirty[i]
+		}
+	}
+	this.dirty = this.dirty[:0]
+}
+
+func (this *Engine) getBuffer(name string) *buffer {
+	buf, ok := this.buffers[name]
+	if !ok {
+		buf = newBuffer()
+		this.buffers[name] = buf
+	}
+	return buf
+}
+
+// attribPointer points the attribute called name in prog at buffer id, with
+// size floats per vertex starting offset floats in.
+func (this *Engine) attribPointer(prog, name string, id uint32, size, offset int) error {
+	attrib, err := this.getAttrib(prog, name)
+	if err != nil {
+		return err
+	}
+	this.UseProgram(prog)
+	gl.BindVertexArray(this.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, id)
+	gl.EnableVertexAttribArray(attrib)
+	gl.VertexAttribPointer(attrib, int32(size), gl.FLOAT, false, 0, gl.PtrOffset(offset*4))
+	return nil
+}
+
+// SetBuffer uploads all of data to the buffer called name and feeds it to the
+// attribute of the same name in prog, size floats per vertex.
+func (this *Engine) SetBuffer(prog, name string, data []float32, size int) error {
+	buf := this.getBuffer(name)
+	buf.set(data)
+	return this.attribPointer(prog, name, buf.id, size, 0)
+}
+
+// StreamBuffer is SetBuffer for data that changes a little every frame: only
+// the ranges passed to MarkBufferDirty since the last call are uploaded.
+func (this *Engine) StreamBuffer(prog, name string, data []float32, size int) error {
+	buf := this.getBuffer(name)
+	buf.flush(data)
+	return this.attribPointer(prog, name, buf.id, size, 0)
+}
+
+// SetBufferUsage sets the usage hint (gl.STATIC_DRAW, gl.DYNAMIC_DRAW,
+// gl.STREAM_DRAW, ...) the buffer called name is allocated with. It takes
+// effect at the next upload.
+func (this *Engine) SetBufferUsage(name string, usage uint32) {
+	buf := this.getBuffer(name)
+	if buf.usage != usage {
+		buf.usage = usage
+		buf.size = -1
+	}
+}
+
+// UpdateBuffer immediately overwrites part of the buffer called name,
+// starting offset floats in.
+func (this *Engine) UpdateBuffer(name string, offset int, data []float32) error {
+	buf, ok := this.buffers[name]
+	if !ok {
+		return fmt.Errorf("no buffer %s", name)
+	}
+	return buf.update(offset, data)
+}
+
+// MarkBufferDirty records that floats start to end (exclusive) of the data
+// behind the buffer called name changed, for the next StreamBuffer.
+func (this *Engine) MarkBufferDirty(name string, start, end int) {
+	this.getBuffer(name).markDirty(start, end)
+}
+
+// A ringBuffer streams data that is rewritten every frame. It is split into
+// segments that are written in turn through an unsynchronized mapping; a
+// fence per segment keeps a write from landing on data the GPU still reads.
+// OpenGL 4.3 has no persistent mapping (glBufferStorage is 4.4), so each
+// write maps just its own segment.
+type ringBuffer struct {
+	id       uint32
+	segment  int
+	segments int
+	current  int
+	fences   []uintptr
+}
+
+// MakeRingBuffer creates a ring buffer of segments segments of segment floats.
+func (this *Engine) MakeRingBuffer(name string, segment, segments int) {
+	if old, ok := this.rings[name]; ok {
+		this.deleteRing(old)
+	}
+	ring := &ringBuffer{segment: segment, segments: segments, current: segments - 1,
+		fences: make([]uintptr, segments)}
+	gl.GenBuffers(1, &ring.id)
+	gl.BindBuffer(gl.ARRAY_BUFFER, ring.id)
+	gl.BufferData(gl.ARRAY_BUFFER, segment*segments*4, nil, gl.STREAM_DRAW)
+	this.rings[name] = ring
+}
+func (this *Engine) deleteRing(ring *ringBuffer) {
+	for _, fence := range ring.fences {
+		if fence != 0 {
+			gl.DeleteSync(fence)
+		}
+	}
+	gl.DeleteBuffers(1, &ring.id)
+}
+
+// WriteRingBuffer copies data into the next segment of the ring buffer called
+// name, waiting for the GPU to finish with it first, and returns the offset in
+// floats at which data starts.
+func (this *Engine) WriteRingBuffer(name string, data []float32) (int, error) {
+	ring, ok := this.rings[name]
+	if !ok {
+		return 0, fmt.Errorf("no ring buffer %s", name)
+	}
+	if len(data) > ring.segment {
+		return 0, fmt.Errorf("%d floats do not fit ring buffer %s segments of %d", len(data), name, ring.segment)
+	}
+	ring.current = (ring.current + 1) % ring.segments
+	if fence := ring.fences[ring.current]; fence != 0 {
+		gl.ClientWaitSync(fence, gl.SYNC_FLUSH_COMMANDS_BIT, 1000000000)
+		gl.DeleteSync(fence)
+		ring.fences[ring.current] = 0
+	}
+	offset := ring.current * ring.segment
+	if len(data) == 0 {
+		return offset, nil
+	}
+	gl.BindBuffer(gl.ARRAY_BUFFER, ring.id)
+	ptr := gl.MapBufferRange(gl.ARRAY_BUFFER, offset*4, len(data)*4,
+		gl.MAP_WRITE_BIT|gl.MAP_UNSYNCHRONIZED_BIT|gl.MAP_INVALIDATE_RANGE_BIT)
+	if ptr == nil {
+		return 0, fmt.Errorf("failed to map ring buffer %s", name)
+	}
+	copy((*[1 << 28]float32)(ptr)[:len(data):len(data)], data)
+	gl.UnmapBuffer(gl.ARRAY_BUFFER)
+	return offset, nil
+}
+
+// FenceRingBuffer marks the segment last written to the ring buffer called
+// name as in use by the draws issued since. Call it after those draws.
+func (this *Engine) FenceRingBuffer(name string) {
+	if ring, ok := this.rings[name]; ok {
+		if ring.fences[ring.current] != 0 {
+			gl.DeleteSync(ring.fences[ring.current])
+		}
+		ring.fences[ring.current] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	}
+}
+
+// RingAttrib feeds the attribute called attrib in prog from the ring buffer
+// called name, size floats per vertex starting offset floats in.
+func (this *Engine) RingAttrib(prog, attrib, name string, size, offset int) error {
+	ring, ok := this.rings[name]
+	if !ok {
+		return fmt.Errorf("no ring buffer %s", name)
+	}
+	return this.attribPointer(prog, attrib, ring.id, size, offset)
+}
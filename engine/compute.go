@@ -0,0 +1,19 @@
+package engine
+
+import (
+	"github.com/go-gl/gl/v4.3-core/gl"
+)
+
+// Dispatch runs the compute program prog over x*y*z work groups, then issues a
+// barrier so shader storage writes are visible to later draws and reads.
+func (this *Engine) Dispatch(prog string, x, y, z uint32) {
+	this.UseProgram(prog)
+	gl.DispatchCompute(x, y, z)
+	gl.MemoryBarrier(gl.SHADER_STORAGE_BARRIER_BIT | gl.VERTEX_ATTRIB_ARRAY_BARRIER_BIT | gl.BUFFER_UPDATE_BARRIER_BIT)
+}
+
+// PatchVertices sets the number of vertices per patch for programs with
+// tessellation stages, which are drawn with gl.PATCHES.
+func (this *Engine) PatchVertices(n int) {
+	gl.PatchParameteri(gl.PATCH_VERTICES, int32(n))
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+type WindowMode int
+
+const (
+	Windowed WindowMode = iota
+	// Fullscreen takes over a monitor, switching it to the chosen video mode.
+	Fullscreen
+	// Borderless covers a monitor with an undecorated window in the video
+	// mode it is already in, which makes switching away quick.
+	Borderless
+)
+
+type VideoMode struct {
+	Width, Height, RefreshRate int
+}
+
+// A Monitor describes a connected monitor, its current video mode and every
+// mode it supports.
+type Monitor struct {
+	Name    string
+	Current VideoMode
+	Modes   []VideoMode
+}
+
+func videoMode(mode *glfw.VidMode) VideoMode {
+	return VideoMode{Width: mode.Width, Height: mode.Height, RefreshRate: mode.RefreshRate}
+}
+
+// Monitors lists the connected monitors, primary first.
+func (this *Engine) Monitors() []Monitor {
+	var ans []Monitor
+	for _, monitor := range glfw.GetMonitors() {
+		info := Monitor{Name: monitor.GetName(), Current: videoMode(monitor.GetVideoMode())}
+		for _, mode := range monitor.GetVideoModes() {
+			info.Modes = append(info.Modes, videoMode(mode))
+		}
+		ans = append(ans, info)
+	}
+	return ans
+}
+
+// SetWindowMode puts the window in mode on the monitor numbered monitor in
+// Monitors. Fullscreen uses video, or the monitor's current mode if video is
+// zero; the other modes ignore it. Windowed restores the window's last
+// windowed position and size. The GL context and everything in it survive.
+// Leaving Windowed grabs the mouse.
+func (this *Engine) SetWindowMode(mode WindowMode, monitor int, video VideoMode) error {
+	monitors := glfw.GetMonitors()
+	if monitor < 0 || monitor >= len(monitors) {
+		return fmt.Errorf("no monitor %d", monitor)
+	}
+	target := monitors[monitor]
+	current := target.GetVideoMode()
+	if this.windowMode == Windowed {
+		this.windowedPos[0], this.windowedPos[1] = this.win.GetPos()
+		this.windowedSize[0], this.windowedSize[1] = this.win.GetSize()
+	}
+	switch mode {
+	case Windowed:
+		this.win.SetAttrib(glfw.Decorated, glfw.True)
+		this.win.SetMonitor(nil, this.windowedPos[0], this.windowedPos[1],
+			this.windowedSize[0], this.windowedSize[1], 0)
+	case Fullscreen:
+		if video == (VideoMode{}) {
+			video = videoMode(current)
+		}
+		supported := false
+		for _, m := range target.GetVideoModes() {
+			if videoMode(m) == video {
+				supported = true
+			}
+		}
+		if !supported {
+			return fmt.Errorf("monitor %d has no video mode %dx%d@%d", monitor, video.Width, video.Height, video.RefreshRate)
+		}
+		this.win.SetMonitor(target, 0, 0, video.Width, video.Height, video.RefreshRate)
+	case Borderless:
+		x, y := target.GetPos()
+		this.win.SetAttrib(glfw.Decorated, glfw.False)
+		this.win.SetMonitor(nil, x, y, current.Width, current.Height, 0)
+	default:
+		return fmt.Errorf("unknown window mode %d", mode)
+	}
+	this.windowMode, this.WindowMonitor = mode, monitor
+	if mode != Windowed {
+		this.fullscreenMode = mode
+		this.GrabMouse(true)
+	}
+	return nil
+}
+func (this *Engine) WindowMode() WindowMode {
+	return this.windowMode
+}
+
+// ToggleFullscreen switches between windowed and the last fullscreen or
+// borderless mode used, Fullscreen at first, on the current monitor.
+func (this *Engine) ToggleFullscreen() error {
+	if this.windowMode != Windowed {
+		return this.SetWindowMode(Windowed, this.WindowMonitor, VideoMode{})
+	}
+	mode := this.fullscreenMode
+	if mode == Windowed {
+		mode = Fullscreen
+	}
+	return this.SetWindowMode(mode, this.WindowMonitor, VideoMode{})
+}
@@ -4,7 +4,7 @@ import (
 	"../input"
 	"fmt"
 	"github.com/go-gl/gl/v4.3-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 	"runtime"
 	"strings"
@@ -17,22 +17,59 @@ type App interface {
 }
 
 type Engine struct {
-	Title         string
-	App           App
+	Title string
+	App   App
+	// The size of the window to open, in screen coordinates. From the first
+	// Tick on, the size of its framebuffer in pixels.
 	Width, Height float32
+	// The window mode and monitor the window opens in. Alt+Enter toggles
+	// between windowed and fullscreen.
+	StartMode     WindowMode
+	WindowMonitor int
+	// The swap interval, and the most frames a second Tick allows, if
+	// positive. Set them before the first Tick, or with SetVSync and
+	// SetMaxFPS.
+	VSync  VSync
+	MaxFPS float32
 
-	vao        uint32
-	programs   map[string]uint32
-	uniforms   map[string](map[string]uint32)
-	attribs    map[string](map[string]uint32)
-	buffers    map[string]uint32
-	win        *glfw.Window
-	inited     bool
-	input      input.Input
-	lastTime   float64
-	lastCursor mgl32.Vec2
-	scroll     mgl32.Vec2
-	keyPresses map[glfw.Key]bool
+	vao            uint32
+	programs       map[string]uint32
+	uniforms       map[string](map[string]variable)
+	attribs        map[string](map[string]variable)
+	buffers        map[string]*buffer
+	rings          map[string]*ringBuffer
+	meshes         map[string]*Mesh
+	textures       map[string]*Texture
+	renderTargets  map[string]*RenderTarget
+	fonts          map[string]*Font
+	postVao        uint32
+	viewport       [2]int
+	blockBuffers   map[string]*blockBuffer
+	win            *glfw.Window
+	inited         bool
+	input          input.Input
+	lastTime       float64
+	lastCursor     mgl32.Vec2
+	scroll         mgl32.Vec2
+	events         []Event
+	frameEvents    []Event
+	inputState     inputState
+	recording      *Recording
+	playback       *Recording
+	playFrame      int
+	playKeys       map[glfw.Key]bool
+	windowMode     WindowMode
+	fullscreenMode WindowMode
+	windowedPos    [2]int
+	windowedSize   [2]int
+	// Kept up to date by callbacks.
+	framebufferSize [2]int
+	contentScale    mgl32.Vec2
+	// The last frameStatsWindow frame times, a ring from nextFrameTime.
+	frameTimes    []float32
+	nextFrameTime int
+	focused       bool
+	drops         []string
 }
 
 func (this *Engine) scrollCallback(win *glfw.Window, xoff, yoff float64) {
@@ -57,6 +94,7 @@ func (this *Engine) init() {
 	}
 		last := glfw.GetTime()
 	glfw.WindowHint(glfw.Resizable, glfw.True)
+	glfw.WindowHint(glfw.ScaleToMonitor, glfw.True)
 	glfw.WindowHint(glfw.ContextVersionMajor, 4)
 	glfw.WindowHint(glfw.ContextVersionMinor, 3)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
@@ -68,13 +106,23 @@ func (this *Engine) init() {
 	} else {
 		this.win = window
 	}
+
+	if this.StartMode != Windowed {
+		if err := this.SetWindowMode(this.StartMode, this.WindowMonitor, VideoMode{}); err != nil {
+			fmt.Println(err)
+		}
+	}
 	
 	this.win.MakeContextCurrent()
 	if err := gl.Init(); err != nil {
 		panic(err)
 	}
+	if err := this.SetVSync(this.VSync); err != nil {
+		fmt.Println(err)
+	}
 
 	gl.GenVertexArrays(1, &(this.vao))
+	gl.GenVertexArrays(1, &(this.postVao))
 	gl.BindVertexArray(this.vao)
 
 	gl.Enable(gl.DEPTH_TEST)
@@ -82,22 +130,46 @@ func (this *Engine) init() {
 	gl.ClearColor(0.0, 0.0, 0.0, 1.0)
 
 	this.programs = make(map[string]uint32)
-	this.uniforms = make(map[string](map[string]uint32))
-	this.attribs = make(map[string](map[string]uint32))
-	this.buffers = make(map[string]uint32)
-	this.keyPresses = make(map[glfw.Key]bool)
+	this.uniforms = make(map[string](map[string]variable))
+	this.attribs = make(map[string](map[string]variable))
+	this.buffers = make(map[string]*buffer)
+	this.rings = make(map[string]*ringBuffer)
+	this.meshes = make(map[string]*Mesh)
+	this.textures = make(map[string]*Texture)
+	this.renderTargets = make(map[string]*RenderTarget)
+	this.fonts = make(map[string]*Font)
+	this.blockBuffers = make(map[string]*blockBuffer)
+	this.inputState = newInputState()
 	this.lastTime = glfw.GetTime()
 	{
 		x, y := this.win.GetCursorPos()
 		this.lastCursor[0], this.lastCursor[1] = float32(x), float32(y)
 	}
 	this.win.SetScrollCallback(this.scrollCallback)
+	this.win.SetKeyCallback(this.keyCallback)
+	this.win.SetCharCallback(this.charCallback)
+	this.win.SetMouseButtonCallback(this.mouseButtonCallback)
+	this.win.SetCursorEnterCallback(this.cursorEnterCallback)
+	this.win.SetFocusCallback(this.focusCallback)
+	this.win.SetFramebufferSizeCallback(this.framebufferSizeCallback)
+	this.win.SetContentScaleCallback(this.contentScaleCallback)
+	this.win.SetDropCallback(this.dropCallback)
+	this.focused = this.win.GetAttrib(glfw.Focused) == glfw.True
+	{
+		width, height := this.win.GetFramebufferSize()
+		this.framebufferSizeCallback(this.win, width, height)
+		x, y := this.win.GetContentScale()
+		this.contentScaleCallback(this.win, x, y)
+	}
 	this.input.Get()
 	
 	this.App.Init(this,&this.input)
 	fmt.Printf("\nEngine init took %v\n", last-glfw.GetTime())
 }
 func (this *Engine) GrabMouse(grab bool) {
+	if grab != this.IsMouseGrabbed() {
+		this.input.Mouse.Recenter()
+	}
 	if grab {
 		this.win.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
 	} else {
@@ -107,6 +179,16 @@ func (this *Engine) GrabMouse(grab bool) {
 func (this *Engine) IsMouseGrabbed() bool {
 	return this.win.GetInputMode(glfw.CursorMode) == glfw.CursorDisabled
 }
+
+// SetMouseSettings sets how mouse movement becomes input.Mouse.Look and
+// recenters the virtual stick.
+func (this *Engine) SetMouseSettings(settings input.MouseSettings) {
+	this.input.Mouse.Settings = settings
+	this.input.Mouse.Recenter()
+}
+func (this *Engine) MouseSettings() input.MouseSettings {
+	return this.input.Mouse.Settings
+}
 func (this *Engine) Tick() bool {
 	runtime.LockOSThread()
 	if !this.inited {
@@ -117,6 +199,7 @@ func (this *Engine) Tick() bool {
 	now := glfw.GetTime()
 	elapsed := float32(now - this.lastTime)
 	this.lastTime = now
+	this.countFrame(elapsed)
 
 	gl.BindVertexArray(this.vao)
 
@@ -132,54 +215,61 @@ func (this *Engine) Tick() bool {
 		this.input.Mouse.Scroll = this.scroll
 		this.scroll = mgl32.Vec2{}
 		this.lastCursor[0], this.lastCursor[1] = float32(x), float32(y)
+		if this.IsMouseGrabbed() {
+			this.input.Mouse.Update(elapsed)
+		} else {
+			this.input.Mouse.Recenter()
+		}
+	}
+	events := this.events
+	this.events = nil
+	this.focusEvents(events)
+	if this.playback != nil {
+		elapsed, events = this.nextFrame()
+	} else {
+		this.playKeys = nil
+	}
+	this.processEvents(events)
+	if this.recording != nil {
+		this.recordFrame(elapsed)
 	}
 
-	h, w := this.win.GetSize()
-	gl.Viewport(0, 0, int32(h), int32(w))
-	this.Width = float32(w)
-	this.Height = float32(h)
+	this.resize()
+	this.dropFiles()
 
-	if !this.App.Tick(this, &this.input, elapsed) || this.win.ShouldClose() {
+	if !this.App.Tick(this, &this.input, elapsed) || this.closing() {
 		this.quit()
 		return false
 	}
 	this.win.SwapBuffers()
+	this.pace()
 	glfw.PollEvents()
 	return true
 }
 func (this *Engine) quit() {
 	this.App.Quit(this)
+	for name := range this.renderTargets {
+		this.DeleteRenderTarget(name)
+	}
+	for name := range this.textures {
+		this.DeleteTexture(name)
+	}
+	for name := range this.meshes {
+		this.DeleteMesh(name)
+	}
+	this.input.StopRumble()
 	glfw.Terminate()
 }
 func (this *Engine) GetKey(key glfw.Key) bool {
+	if this.playKeys != nil {
+		return this.playKeys[key]
+	}
 	return this.win.GetKey(key) == glfw.Press
 }
+
+// GetKeyPressed is KeyPressed.
 func (this *Engine) GetKeyPressed(key glfw.Key) bool {
-	ret := this.GetKey(key)
-	if ret {
-		if this.keyPresses[key] {
-			return false
-		} else {
-			this.keyPresses[key] = true
-			return true
-		}
-	} else {
-		this.keyPresses[key] = false
-		return false
-	}
-}
-func (this *Engine) SetBuffer(prog, name string, data []float32, size int) {
-	this.UseProgram(prog)
-	buf, ok := this.buffers[name]
-	if !ok {
-		gl.GenBuffers(1, &buf)
-		this.buffers[name] = buf
-	}
-	gl.BindBuffer(gl.ARRAY_BUFFER, buf)
-	gl.BufferData(gl.ARRAY_BUFFER, len(data)*4, gl.Ptr(data), gl.STATIC_DRAW)
-	attrib := this.getAttrib(prog, name)
-	gl.EnableVertexAttribArray(attrib)
-	gl.VertexAttribPointer(attrib, int32(size), gl.FLOAT, false, 0, gl.PtrOffset(0))
+	return this.KeyPressed(key)
 }
 func (this *Engine) FragLocation(prog, out string) {
 	this.UseProgram(prog)
@@ -188,66 +278,39 @@ func (this *Engine) FragLocation(prog, out string) {
 func (this *Engine) UseProgram(prog string) {
 	gl.UseProgram(this.programs[prog])
 }
-func (this *Engine) getLoc(program, uniform string) uint32 {
-	this.UseProgram(program)
-	if _, ok := this.uniforms[program]; !ok {
-		this.uniforms[program] = make(map[string]uint32)
-	}
-	if _, ok := this.uniforms[program][uniform]; !ok {
-		this.uniforms[program][uniform] =
-			uint32(gl.GetUniformLocation(this.programs[program], gl.Str(uniform+"\x00")))
-	}
-	return this.uniforms[program][uniform]
-}
-func (this *Engine) getAttrib(program, attrib string) uint32 {
-	this.UseProgram(program)
-	if _, ok := this.attribs[program]; !ok {
-		this.attribs[program] = make(map[string]uint32)
-	}
-	if _, ok := this.attribs[program][attrib]; !ok {
-		this.attribs[program][attrib] =
-			uint32(gl.GetAttribLocation(this.programs[program], gl.Str(attrib+"\x00")))
-	}
-	return this.attribs[program][attrib]
-}
 
-func (this *Engine) UniformMatrix(program, uniform string, matrix mgl32.Mat4) {
-	uni := this.getLoc(program, uniform)
-	gl.UniformMatrix4fv(int32(uni), 1, false, &matrix[0])
-}
-func (this *Engine) UniformFloat(program, uniform string, float float32) {
-	uni := this.getLoc(program, uniform)
-	gl.Uniform1f(int32(uni), float)
-}
-func (this *Engine) UniformVecs(program, uniform string, arr []float32){
-	uni := this.getLoc(program, uniform)
-	gl.Uniform3fv(int32(uni),int32(len(arr)/3),&arr[0])
-}
-
-func (this *Engine) MakeProgramOrPanic(name, vertexShaderSource, geometryShaderSource, fragmentShaderSource string) {
-	vertexShader, err := compileShader(vertexShaderSource, gl.VERTEX_SHADER)
-	if err != nil {
-		panic(err)
+// MakeProgram compiles and links a program from an arbitrary set of stages,
+// keyed by shader type (gl.VERTEX_SHADER, gl.TESS_CONTROL_SHADER,
+// gl.TESS_EVALUATION_SHADER, gl.GEOMETRY_SHADER, gl.FRAGMENT_SHADER or
+// gl.COMPUTE_SHADER). A compute stage must be the only stage of its program.
+func (this *Engine) MakeProgram(name string, stages map[uint32]string) error {
+	if len(stages) == 0 {
+		return fmt.Errorf("program %s has no stages", name)
 	}
-	defer gl.DeleteShader(vertexShader)
-
-	geometryShader, err := compileShader(geometryShaderSource, gl.GEOMETRY_SHADER)
-	if err != nil {
-		panic(err)
+	if _, ok := stages[gl.COMPUTE_SHADER]; ok && len(stages) != 1 {
+		return fmt.Errorf("program %s mixes a compute stage with other stages", name)
 	}
-	defer gl.DeleteShader(geometryShader)
-
-	fragmentShader, err := compileShader(fragmentShaderSource, gl.FRAGMENT_SHADER)
-	if err != nil {
-		panic(err)
+	if _, ok := stages[gl.COMPUTE_SHADER]; !ok {
+		if _, ok := stages[gl.VERTEX_SHADER]; !ok {
+			return fmt.Errorf("program %s has no vertex stage", name)
+		}
+	}
+	if _, ok := stages[gl.TESS_CONTROL_SHADER]; ok {
+		if _, ok := stages[gl.TESS_EVALUATION_SHADER]; !ok {
+			return fmt.Errorf("program %s has a tessellation control stage but no evaluation stage", name)
+		}
 	}
-	defer gl.DeleteShader(fragmentShader)
 
 	program := gl.CreateProgram()
-
-	gl.AttachShader(program, vertexShader)
-	gl.AttachShader(program, geometryShader)
-	gl.AttachShader(program, fragmentShader)
+	for shaderType, source := range stages {
+		shader, err := compileShader(source, shaderType)
+		if err != nil {
+			gl.DeleteProgram(program)
+			return err
+		}
+		gl.AttachShader(program, shader)
+		defer gl.DeleteShader(shader)
+	}
 	gl.LinkProgram(program)
 
 	var status int32
@@ -259,10 +322,27 @@ func (this *Engine) MakeProgramOrPanic(name, vertexShaderSource, geometryShaderS
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
 
-		panic(fmt.Errorf("failed to link program: %v", log))
+		gl.DeleteProgram(program)
+		return fmt.Errorf("failed to link program %s: %v", name, log)
 	}
 
+	if old, ok := this.programs[name]; ok {
+		gl.DeleteProgram(old)
+	}
 	this.programs[name] = program
+	this.uniforms[name] = introspect(program, gl.ACTIVE_UNIFORMS, gl.ACTIVE_UNIFORM_MAX_LENGTH,
+		gl.GetActiveUniform, gl.GetUniformLocation)
+	this.attribs[name] = introspect(program, gl.ACTIVE_ATTRIBUTES, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH,
+		gl.GetActiveAttrib, gl.GetAttribLocation)
+	return nil
+}
+func (this *Engine) MakeProgramOrPanic(name string, stages map[uint32]string) {
+	if err := this.MakeProgram(name, stages); err != nil {
+		panic(err)
+	}
+}
+func (this *Engine) MakeComputeProgramOrPanic(name, computeShaderSource string) {
+	this.MakeProgramOrPanic(name, map[uint32]string{gl.COMPUTE_SHADER: computeShaderSource})
 }
 
 func compileShader(source string, shaderType uint32) (uint32, error) {
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+type EventKind int
+
+const (
+	// A key was pressed, repeated or released.
+	EventKey EventKind = iota
+	// A character was typed.
+	EventChar
+	// A mouse button was pressed or released.
+	EventMouseButton
+	// The cursor entered or left the window.
+	EventCursorEnter
+	// The window gained or lost focus.
+	EventFocus
+)
+
+// An Event is one thing GLFW reported happening to the window. Which fields
+// are set depends on Kind: Key, Scancode, Action and Mods for key events,
+// Char for characters, Button, Action and Mods for mouse buttons, and On for
+// cursor enter (entered) and focus (focused) events.
+type Event struct {
+	Kind     EventKind
+	Key      glfw.Key
+	Scancode int
+	Action   glfw.Action
+	Mods     glfw.ModifierKey
+	Char     rune
+	Button   glfw.MouseButton
+	On       bool
+}
+
+// inputState is the state of every key and mouse button this tick, worked
+// out from the events since the last one, so presses and releases between
+// ticks are never lost.
+type inputState struct {
+	keys                      map[glfw.Key]bool
+	keysPressed, keysReleased map[glfw.Key]bool
+	buttons                   map[glfw.MouseButton]bool
+	buttonsPressed            map[glfw.MouseButton]bool
+	buttonsReleased           map[glfw.MouseButton]bool
+	text                      []rune
+}
+
+func newInputState() inputState {
+	return inputState{
+		keys:            make(map[glfw.Key]bool),
+		keysPressed:     make(map[glfw.Key]bool),
+		keysReleased:    make(map[glfw.Key]bool),
+		buttons:         make(map[glfw.MouseButton]bool),
+		buttonsPressed:  make(map[glfw.MouseButton]bool),
+		buttonsReleased: make(map[glfw.MouseButton]bool),
+	}
+}
+
+func (this *Engine) keyCallback(win *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if key == glfw.KeyEnter && action == glfw.Press && mods&glfw.ModAlt != 0 {
+		if err := this.ToggleFullscreen(); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	this.events = append(this.events, Event{Kind: EventKey, Key: key, Scancode: scancode, Action: action, Mods: mods})
+}
+func (this *Engine) charCallback(win *glfw.Window, char rune) {
+	this.events = append(this.events, Event{Kind: EventChar, Char: char})
+}
+func (this *Engine) mouseButtonCallback(win *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	this.events = append(this.events, Event{Kind: EventMouseButton, Button: button, Action: action, Mods: mods})
+}
+func (this *Engine) cursorEnterCallback(win *glfw.Window, entered bool) {
+	this.events = append(this.events, Event{Kind: EventCursorEnter, On: entered})
+}
+func (this *Engine) focusCallback(win *glfw.Window, focused bool) {
+	this.events = append(this.events, Event{Kind: EventFocus, On: focused})
+}
+
+// processEvents makes events this tick's events and updates the key and
+// button state from them.
+func (this *Engine) processEvents(events []Event) {
+	this.frameEvents = events
+	state := &this.inputState
+	state.keysPressed = make(map[glfw.Key]bool)
+	state.keysReleased = make(map[glfw.Key]bool)
+	state.buttonsPressed = make(map[glfw.MouseButton]bool)
+	state.buttonsReleased = make(map[glfw.MouseButton]bool)
+	state.text = state.text[:0]
+	for _, event := range events {
+		switch event.Kind {
+		case EventKey:
+			switch event.Action {
+			case glfw.Press:
+				state.keys[event.Key] = true
+				state.keysPressed[event.Key] = true
+			case glfw.Release:
+				state.keys[event.Key] = false
+				state.keysReleased[event.Key] = true
+			}
+		case EventMouseButton:
+			switch event.Action {
+			case glfw.Press:
+				state.buttons[event.Button] = true
+				state.buttonsPressed[event.Button] = true
+			case glfw.Release:
+				state.buttons[event.Button] = false
+				state.buttonsReleased[event.Button] = true
+			}
+		case EventChar:
+			state.text = append(state.text, event.Char)
+		}
+	}
+}
+
+// Events is every event reported since the last tick, in order.
+func (this *Engine) Events() []Event {
+	return this.frameEvents
+}
+
+// Text is the characters typed since the last tick.
+func (this *Engine) Text() string {
+	return string(this.inputState.text)
+}
+
+// KeyPressed is whether key went down since the last tick, even if it has
+// already been released.
+func (this *Engine) KeyPressed(key glfw.Key) bool {
+	return this.inputState.keysPressed[key]
+}
+
+// KeyReleased is whether key came up since the last tick.
+func (this *Engine) KeyReleased(key glfw.Key) bool {
+	return this.inputState.keysReleased[key]
+}
+
+// KeyHeld is whether key is down as of the last event about it.
+func (this *Engine) KeyHeld(key glfw.Key) bool {
+	return this.inputState.keys[key]
+}
+
+// MouseButtonPressed is whether button went down since the last tick.
+func (this *Engine) MouseButtonPressed(button glfw.MouseButton) bool {
+	return this.inputState.buttonsPressed[button]
+}
+
+// MouseButtonReleased is whether button came up since the last tick.
+func (this *Engine) MouseButtonReleased(button glfw.MouseButton) bool {
+	return this.inputState.buttonsReleased[button]
+}
+
+// MouseButtonHeld is whether button is down as of the last event about it.
+func (this *Engine) MouseButtonHeld(button glfw.MouseButton) bool {
+	return this.inputState.buttons[button]
+}
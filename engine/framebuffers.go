@@ -0,0 +1,274 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+)
+
+// A RenderTarget is a framebuffer object with one or more color textures and
+// an optional depth buffer. It is sized as a fraction of the viewport and
+// resized along with it. Its color textures are registered with the engine
+// as name, name.1, name.2, ... so they can be bound like any other texture.
+type RenderTarget struct {
+	name          string
+	fbo           uint32
+	depth         uint32
+	hasDepth      bool
+	formats       []uint32
+	colors        []*Texture
+	Scale         float32
+	Width, Height int
+	// Why the last resize failed, if it did.
+	err error
+}
+
+// MakeRenderTarget creates the render target called name with a color
+// attachment of each internal format in formats (gl.RGBA8, or gl.RGBA16F for
+// HDR) and, if depth is set, a depth buffer. Its size is scale times the
+// viewport size.
+func (this *Engine) MakeRenderTarget(name string, scale float32, formats []uint32, depth bool) (*RenderTarget, error) {
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("render target %s has no color attachments", name)
+	}
+	this.DeleteRenderTarget(name)
+	target := &RenderTarget{name: name, hasDepth: depth, formats: formats, Scale: scale}
+	gl.GenFramebuffers(1, &target.fbo)
+	if depth {
+		gl.GenRenderbuffers(1, &target.depth)
+	}
+	for i := range formats {
+		tex := this.newTexture(target.textureName(i), gl.TEXTURE_2D, 0, 0, 1, false)
+		tex.finish()
+		target.colors = append(target.colors, tex)
+	}
+	this.renderTargets[name] = target
+	if err := target.resize(this.viewport[0], this.viewport[1]); err != nil {
+		this.DeleteRenderTarget(name)
+		return nil, err
+	}
+	return target, nil
+}
+func (this *RenderTarget) textureName(i int) string {
+	if i == 0 {
+		return this.name
+	}
+	return fmt.Sprintf("%s.%d", this.name, i)
+}
+func (this *RenderTarget) resize(viewportWidth, viewportHeight int) error {
+	width := int(float32(viewportWidth) * this.Scale)
+	height := int(float32(viewportHeight) * this.Scale)
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	if width == this.Width && height == this.Height {
+		return nil
+	}
+	return this.setSize(width, height)
+}
+
+// setSize reallocates the attachments at width by height pixels.
+func (this *RenderTarget) setSize(width, height int) error {
+	this.Width, this.Height = width, height
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, this.fbo)
+	attachments := make([]uint32, len(this.colors))
+	for i, tex := range this.colors {
+		tex.Width, tex.Height = width, height
+		gl.BindTexture(gl.TEXTURE_2D, tex.id)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, int32(this.formats[i]), int32(width), int32(height), 0,
+			gl.RGBA, gl.FLOAT, nil)
+		attachments[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachments[i], gl.TEXTURE_2D, tex.id, 0)
+	}
+	gl.DrawBuffers(int32(len(attachments)), &attachments[0])
+	if this.hasDepth {
+		gl.BindRenderbuffer(gl.RENDERBUFFER, this.depth)
+		gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT24, int32(width), int32(height))
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, this.depth)
+	}
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		return fmt.Errorf("render target %s is incomplete: status 0x%x", this.name, status)
+	}
+	return nil
+}
+func (this *Engine) resizeRenderTargets(width, height int) {
+	if this.viewport == [2]int{width, height} {
+		return
+	}
+	this.viewport = [2]int{width, height}
+	for _, target := range this.renderTargets {
+		oldWidth, oldHeight := target.Width, target.Height
+		target.err = target.resize(width, height)
+		if target.err != nil {
+			if err := target.setSize(oldWidth, oldHeight); err != nil {
+				target.err = fmt.Errorf("%v; restoring %dx%d: %v", target.err, oldWidth, oldHeight, err)
+			}
+		}
+	}
+}
+
+// Err is why the render target could not follow the last change of viewport
+// size, in which case it kept its old size, or nil.
+func (this *RenderTarget) Err() error {
+	return this.err
+}
+
+// Viewport is the size of the window in pixels as of the start of the tick.
+func (this *Engine) Viewport() (width, height int) {
+	return this.viewport[0], this.viewport[1]
+}
+
+func (this *Engine) RenderTarget(name string) *RenderTarget {
+	return this.renderTargets[name]
+}
+
+// BindRenderTarget directs drawing, and the viewport, to the render target
+// called name.
+func (this *Engine) BindRenderTarget(name string) error {
+	target, ok := this.renderTargets[name]
+	if !ok {
+		return fmt.Errorf("no render target %s", name)
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, target.fbo)
+	gl.Viewport(0, 0, int32(target.Width), int32(target.Height))
+	return nil
+}
+
+// BindDefaultFramebuffer directs drawing, and the viewport, back to the window.
+func (this *Engine) BindDefaultFramebuffer() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, int32(this.viewport[0]), int32(this.viewport[1]))
+}
+
+// SwapRenderTargets exchanges the render targets called a and b, along with
+// their textures. Alternating between two targets this way lets a pass read
+// last frame's result while writing this frame's, e.g. for accumulation.
+func (this *Engine) SwapRenderTargets(a, b string) error {
+	ta, ok := this.renderTargets[a]
+	if !ok {
+		return fmt.Errorf("no render target %s", a)
+	}
+	tb, ok := this.renderTargets[b]
+	if !ok {
+		return fmt.Errorf("no render target %s", b)
+	}
+	if len(ta.colors) != len(tb.colors) {
+		return fmt.Errorf("render targets %s and %s have different attachments", a, b)
+	}
+	ta.name, tb.name = b, a
+	this.renderTargets[a], this.renderTargets[b] = tb, ta
+	for i := range ta.colors {
+		this.textures[ta.textureName(i)], this.textures[tb.textureName(i)] = ta.colors[i], tb.colors[i]
+	}
+	return nil
+}
+
+// DeleteRenderTarget frees the render target called name and its textures.
+func (this *Engine) DeleteRenderTarget(name string) {
+	if target, ok := this.renderTargets[name]; ok {
+		for i := range target.colors {
+			this.DeleteTexture(target.textureName(i))
+		}
+		if target.hasDepth {
+			gl.DeleteRenderbuffers(1, &target.depth)
+		}
+		gl.DeleteFramebuffers(1, &target.fbo)
+		delete(this.renderTargets, name)
+	}
+}
+
+// PostVertexShader is the vertex stage of post-processing programs. It draws
+// a triangle covering the viewport and passes texture coordinates to the
+// fragment stage as uv.
+const PostVertexShader = `
+#version 430
+out vec2 uv;
+void main() {
+  uv = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+  gl_Position = vec4(uv * 2.0 - 1.0, 0.0, 1.0);
+}
+`
+
+// MakePostProgramOrPanic makes a post-processing program from a fragment
+// stage that reads uv.
+func (this *Engine) MakePostProgramOrPanic(name, fragmentShaderSource string) {
+	this.MakeProgramOrPanic(name, map[uint32]string{
+		gl.VERTEX_SHADER:   PostVertexShader,
+		gl.FRAGMENT_SHADER: fragmentShaderSource,
+	})
+}
+
+// A PostPass draws Program over the whole of Output, the name of a render
+// target or "" for the window. Inputs maps sampler uniforms of Program to the
+// textures they read. Setup, if set, runs first to set other uniforms, and
+// Additive blends the result onto what Output already holds.
+type PostPass struct {
+	Program  string
+	Inputs   map[string]string
+	Output   string
+	Setup    func(*Engine)
+	Additive bool
+}
+
+// A PostChain is a list of post-processing passes run in order.
+type PostChain struct {
+	engine *Engine
+	passes []PostPass
+}
+
+func (this *Engine) NewPostChain() *PostChain {
+	return &PostChain{engine: this}
+}
+
+// Add appends pass to the chain and returns the chain.
+func (this *PostChain) Add(pass PostPass) *PostChain {
+	this.passes = append(this.passes, pass)
+	return this
+}
+
+// Pass appends a pass of program reading inputs into output and returns the chain.
+func (this *PostChain) Pass(program, output string, inputs map[string]string) *PostChain {
+	return this.Add(PostPass{Program: program, Output: output, Inputs: inputs})
+}
+
+// Run draws every pass in order, then leaves the window bound.
+func (this *PostChain) Run() error {
+	engine := this.engine
+	gl.Disable(gl.DEPTH_TEST)
+	defer gl.Enable(gl.DEPTH_TEST)
+	defer engine.BindDefaultFramebuffer()
+	gl.BindVertexArray(engine.postVao)
+	defer gl.BindVertexArray(engine.vao)
+	for _, pass := range this.passes {
+		if pass.Output == "" {
+			engine.BindDefaultFramebuffer()
+		} else if err := engine.BindRenderTarget(pass.Output); err != nil {
+			return err
+		}
+		engine.UseProgram(pass.Program)
+		var unit uint32
+		for uniform, texture := range pass.Inputs {
+			if err := engine.BindTexture(pass.Program, uniform, texture, unit); err != nil {
+				return err
+			}
+			unit++
+		}
+		if pass.Setup != nil {
+			pass.Setup(engine)
+		}
+		if pass.Additive {
+			gl.Enable(gl.BLEND)
+			gl.BlendFunc(gl.ONE, gl.ONE)
+		}
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+		if pass.Additive {
+			gl.Disable(gl.BLEND)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// An App can also implement any of the interfaces below to hear about more
+// of the window's life. The engine finds them by type assertion.
+
+// A Resizer is an App that wants to know when the framebuffer changes size.
+// OnResize is called before the Tick that first sees the new size, and before
+// the first Tick, with the size in pixels. Render targets that could not be
+// resized report why through Err.
+type Resizer interface {
+	OnResize(engine *Engine, width, height int)
+}
+
+// A Focuser is an App that wants to know when the window gains or loses
+// focus. The engine releases the mouse before calling OnBlur; clicking the
+// window, or the app, grabs it again. Focus follows the live window even
+// while a recording plays.
+type Focuser interface {
+	OnFocus(engine *Engine)
+	OnBlur(engine *Engine)
+}
+
+// A CloseRequester is an App that decides whether closing the window quits.
+// OnCloseRequest is called when the user closes it, and the engine carries on
+// if it returns false. An App quitting by returning false from Tick is not
+// asked.
+type CloseRequester interface {
+	OnCloseRequest(engine *Engine) bool
+}
+
+// A FileDropper is an App that accepts files dropped on the window. OnFileDrop
+// is called before the next Tick with their paths. Drops are recorded and
+// played back along with the rest of the input.
+type FileDropper interface {
+	OnFileDrop(engine *Engine, paths []string)
+}
+
+func (this *Engine) dropCallback(win *glfw.Window, names []string) {
+	this.drops = append(this.drops, names...)
+}
+
+// focusEvents applies the focus changes among the window's events. Focus
+// belongs to the live window, so recorded focus events are never applied.
+func (this *Engine) focusEvents(events []Event) {
+	for _, event := range events {
+		if event.Kind == EventFocus {
+			this.focus(event.On)
+		}
+	}
+}
+
+// focus releases the mouse on losing focus and tells the App.
+func (this *Engine) focus(focused bool) {
+	this.focused = focused
+	if !focused {
+		this.GrabMouse(false)
+	}
+	if focuser, ok := this.App.(Focuser); ok {
+		if focused {
+			focuser.OnFocus(this)
+		} else {
+			focuser.OnBlur(this)
+		}
+	}
+}
+
+// Focused reports whether the window has focus, as of the start of the tick.
+func (this *Engine) Focused() bool {
+	return this.focused
+}
+
+// dropFiles hands the files dropped since the last tick to the App.
+func (this *Engine) dropFiles() {
+	drops := this.drops
+	this.drops = nil
+	if dropper, ok := this.App.(FileDropper); ok && len(drops) != 0 {
+		dropper.OnFileDrop(this, drops)
+	}
+}
+
+// closing reports whether the user has closed the window and the App agrees.
+func (this *Engine) closing() bool {
+	if !this.win.ShouldClose() {
+		return false
+	}
+	if requester, ok := this.App.(CloseRequester); ok && !requester.OnCloseRequest(this) {
+		this.win.SetShouldClose(false)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+)
+
+// A Mesh owns a vertex array object, the vertex buffers feeding it, an
+// optional index buffer and the layout tying those buffers to the attributes
+// of one program, so it can be drawn with a single call.
+type Mesh struct {
+	engine   *Engine
+	program  string
+	mode     uint32
+	vao      uint32
+	buffers  map[string]*buffer
+	vertices map[string]int
+	indices  uint32
+	count    int
+	indexed  bool
+}
+
+// MakeMesh creates an empty mesh called name, drawn with program as
+// primitives of type mode (gl.LINES, gl.TRIANGLES, ...). A mesh of the same
+// name is deleted first.
+func (this *Engine) MakeMesh(name, program string, mode uint32) *Mesh {
+	this.DeleteMesh(name)
+	mesh := &Mesh{engine: this, program: program, mode: mode,
+		buffers: make(map[string]*buffer), vertices: make(map[string]int)}
+	gl.GenVertexArrays(1, &mesh.vao)
+	this.meshes[name] = mesh
+	return mesh
+}
+func (this *Engine) Mesh(name string) *Mesh {
+	return this.meshes[name]
+}
+func (this *Engine) DeleteMesh(name string) {
+	if mesh, ok := this.meshes[name]; ok {
+		mesh.delete()
+		delete(this.meshes, name)
+	}
+}
+func (this *Mesh) delete() {
+	for _, buf := range this.buffers {
+		gl.DeleteBuffers(1, &buf.id)
+	}
+	if this.indexed {
+		gl.DeleteBuffers(1, &this.indices)
+	}
+	gl.DeleteVertexArrays(1, &this.vao)
+}
+
+func (this *Mesh) getBuffer(name string) *buffer {
+	buf, ok := this.buffers[name]
+	if !ok {
+		buf = newBuffer()
+		this.buffers[name] = buf
+	}
+	return buf
+}
+func (this *Mesh) layout(name string, buf *buffer, size int) error {
+	attrib, err := this.engine.getAttrib(this.program, name)
+	if err != nil {
+		return err
+	}
+	gl.BindVertexArray(this.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, buf.id)
+	gl.EnableVertexAttribArray(attrib)
+	gl.VertexAttribPointer(attrib, int32(size), gl.FLOAT, false, 0, gl.PtrOffset(0))
+	gl.BindVertexArray(this.engine.vao)
+	this.vertices[name] = buf.size / size
+	return nil
+}
+
+// SetAttrib uploads all of data to the buffer feeding the attribute called
+// name, size floats per vertex.
+func (this *Mesh) SetAttrib(name string, data []float32, size int) error {
+	buf := this.getBuffer(name)
+	buf.set(data)
+	return this.layout(name, buf, size)
+}
+
+// StreamAttrib is SetAttrib for data that changes a little every frame: only
+// the ranges passed to MarkDirty since the last call are uploaded.
+func (this *Mesh) StreamAttrib(name string, data []float32, size int) error {
+	buf := this.getBuffer(name)
+	buf.flush(data)
+	return this.layout(name, buf, size)
+}
+
+// MarkDirty records that floats start to end (exclusive) of the data behind
+// the attribute called name changed, for the next StreamAttrib.
+func (this *Mesh) MarkDirty(name string, start, end int) {
+	this.getBuffer(name).markDirty(start, end)
+}
+
+// SetUsage sets the usage hint the buffer behind the attribute called name is
+// allocated with. It takes effect at the next upload.
+func (this *Mesh) SetUsage(name string, usage uint32) {
+	buf := this.getBuffer(name)
+	if buf.usage != usage {
+		buf.usage = usage
+		buf.size = -1
+	}
+}
+
+// SetIndices makes the mesh indexed, drawing the vertices listed in indices.
+func (this *Mesh) SetIndices(indices []uint32) {
+	gl.BindVertexArray(this.vao)
+	if !this.indexed {
+		gl.GenBuffers(1, &this.indices)
+		this.indexed = true
+	}
+	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, this.indices)
+	if len(indices) == 0 {
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, 0, nil, gl.STATIC_DRAW)
+	} else {
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*4, gl.Ptr(indices), gl.STATIC_DRAW)
+	}
+	gl.BindVertexArray(this.engine.vao)
+	this.count = len(indices)
+}
+
+// Vertices is the number of vertices a non-indexed mesh draws: the fewest any
+// of its attributes has data for.
+func (this *Mesh) Vertices() int {
+	ans := -1
+	for _, n := range this.vertices {
+		if ans < 0 || n < ans {
+			ans = n
+		}
+	}
+	if ans < 0 {
+		return 0
+	}
+	return ans
+}
+
+// Draw draws the whole mesh with its program.
+func (this *Mesh) Draw() error {
+	if _, ok := this.engine.programs[this.program]; !ok {
+		return fmt.Errorf("no program %s", this.program)
+	}
+	this.engine.UseProgram(this.program)
+	gl.BindVertexArray(this.vao)
+	if this.indexed {
+		gl.DrawElements(this.mode, int32(this.count), gl.UNSIGNED_INT, gl.PtrOffset(0))
+	} else {
+		gl.DrawArrays(this.mode, 0, int32(this.Vertices()))
+	}
+	gl.BindVertexArray(this.engine.vao)
+	return nil
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"sort"
+	"time"
+)
+
+type VSync int
+
+const (
+	VSyncOff VSync = iota
+	VSyncOn
+	// VSyncAdaptive waits for vertical blank like VSyncOn, but swaps at once
+	// when a frame is late rather than waiting for the next one, tearing
+	// instead of stuttering. Drivers without it get VSyncOn.
+	VSyncAdaptive
+)
+
+// How many of the most recent frames FrameStats covers.
+const frameStatsWindow = 240
+
+// How long before the end of a capped frame to stop sleeping and spin
+// instead, since sleeps can overshoot by a millisecond or more.
+const spinTime = 2 * time.Millisecond
+
+// FrameStats describes the times in seconds of recent frames, from the
+// start of one Tick to the start of the next.
+type FrameStats struct {
+	Frames                       int
+	Min, Avg, Max, P50, P95, P99 float32
+}
+
+func (this FrameStats) String() string {
+	ms := func(seconds float32) float32 { return seconds * 1000 }
+	return fmt.Sprintf("Frame ms over %d: min %5.2f avg %5.2f max %5.2f p50 %5.2f p95 %5.2f p99 %5.2f\n",
+		this.Frames, ms(this.Min), ms(this.Avg), ms(this.Max), ms(this.P50), ms(this.P95), ms(this.P99))
+}
+
+// SetVSync sets the swap interval. Asking for VSyncAdaptive where the driver
+// lacks it sets VSyncOn and returns an error saying so.
+func (this *Engine) SetVSync(mode VSync) error {
+	this.VSync = mode
+	if !this.inited {
+		return nil
+	}
+	switch mode {
+	case VSyncOff:
+		glfw.SwapInterval(0)
+	case VSyncOn:
+		glfw.SwapInterval(1)
+	case VSyncAdaptive:
+		if glfw.ExtensionSupported("WGL_EXT_swap_control_tear") ||
+			glfw.ExtensionSupported("GLX_EXT_swap_control_tear") {
+			glfw.SwapInterval(-1)
+		} else {
+			this.VSync = VSyncOn
+			glfw.SwapInterval(1)
+			return fmt.Errorf("adaptive vsync is not supported, using vsync")
+		}
+	default:
+		return fmt.Errorf("unknown vsync mode %d", mode)
+	}
+	return nil
+}
+
+// SetMaxFPS caps the frame rate at fps frames per second, or lifts the cap
+// if fps is not positive.
+func (this *Engine) SetMaxFPS(fps float32) {
+	this.MaxFPS = fps
+}
+
+// FrameStats describes the last few seconds of frames.
+func (this *Engine) FrameStats() FrameStats {
+	var ans FrameStats
+	times := append([]float32(nil), this.frameTimes...)
+	if len(times) == 0 {
+		return ans
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	var sum float32
+	for _, t := range times {
+		sum += t
+	}
+	percentile := func(p float32) float32 {
+		return times[int(p*float32(len(times)-1)+0.5)]
+	}
+	ans.Frames = len(times)
+	ans.Min, ans.Max, ans.Avg = times[0], times[len(times)-1], sum/float32(len(times))
+	ans.P50, ans.P95, ans.P99 = percentile(0.5), percentile(0.95), percentile(0.99)
+	return ans
+}
+
+// countFrame adds a frame of elapsed seconds to the stats.
+func (this *Engine) countFrame(elapsed float32) {
+	if len(this.frameTimes) < frameStatsWindow {
+		this.frameTimes = append(this.frameTimes, elapsed)
+	} else {
+		this.frameTimes[this.nextFrameTime] = elapsed
+	}
+	this.nextFrameTime = (this.nextFrameTime + 1) % frameStatsWindow
+}
+
+// pace waits out what is left of the frame under the MaxFPS cap, sleeping
+// for most of it and spinning for the rest.
+func (this *Engine) pace() {
+	if this.MaxFPS <= 0 {
+		return
+	}
+	deadline := this.lastTime + 1/float64(this.MaxFPS)
+	if left := time.Duration((deadline-glfw.GetTime())*float64(time.Second)) - spinTime; left > 0 {
+		time.Sleep(left)
+	}
+	for glfw.GetTime() < deadline {
+	}
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"../input"
+	"encoding/gob"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"os"
+)
+
+// A Frame is all the input an App could read during one tick.
+type Frame struct {
+	Input input.Snapshot
+	// Keys is the keys held down, as reported by GetKey.
+	Keys   []glfw.Key
+	Events []Event
+	// Drops is the paths of files dropped on the window.
+	Drops []string
+	Delta float32
+}
+
+// A Recording is the input of a run tick by tick, along with the seed its
+// simulation started from, so the run can be replayed exactly.
+type Recording struct {
+	Seed   int64
+	Frames []Frame
+}
+
+func LoadRecording(path string) (*Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	recording := &Recording{}
+	if err := gob.NewDecoder(file).Decode(recording); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+func (this *Recording) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(file).Encode(this); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Record starts recording the input of every following tick, with seed as
+// the seed of the simulation.
+func (this *Engine) Record(seed int64) {
+	this.recording = &Recording{Seed: seed}
+}
+
+// StopRecording stops recording and returns what was recorded, or nil if
+// nothing was being recorded.
+func (this *Engine) StopRecording() *Recording {
+	recording := this.recording
+	this.recording = nil
+	return recording
+}
+
+// Play feeds the frames of recording to the following ticks in place of live
+// input, delta times included. Live input resumes once they run out. Call it
+// before the first Tick to replay a run from the start; App.Init can then
+// read the seed with Playback.
+func (this *Engine) Play(recording *Recording) {
+	this.playback, this.playFrame = nil, 0
+	if recording != nil && len(recording.Frames) > 0 {
+		this.playback = recording
+	}
+}
+
+// Playback is the recording being played, or nil.
+func (this *Engine) Playback() *Recording {
+	return this.playback
+}
+
+// nextFrame sets the input and file drops to the next recorded frame and
+// returns its delta and events.
+func (this *Engine) nextFrame() (float32, []Event) {
+	frame := this.playback.Frames[this.playFrame]
+	this.playFrame++
+	if this.playFrame == len(this.playback.Frames) {
+		this.playback = nil
+	}
+	this.input.Restore(frame.Input)
+	this.drops = frame.Drops
+	this.playKeys = make(map[glfw.Key]bool)
+	for _, key := range frame.Keys {
+		this.playKeys[key] = true
+	}
+	return frame.Delta, frame.Events
+}
+
+// recordFrame appends this tick's input to the recording.
+func (this *Engine) recordFrame(delta float32) {
+	frame := Frame{Input: this.input.Snapshot(), Events: this.frameEvents, Drops: this.drops, Delta: delta}
+	for key := glfw.KeySpace; key <= glfw.KeyLast; key++ {
+		if this.GetKey(key) {
+			frame.Keys = append(frame.Keys, key)
+		}
+	}
+	this.recording.Frames = append(this.recording.Frames, frame)
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"github.com/go-gl/gl/v4.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func (this *Engine) framebufferSizeCallback(win *glfw.Window, width, height int) {
+	// A minimized window has no framebuffer; keep drawing at the last size.
+	if width > 0 && height > 0 {
+		this.framebufferSize = [2]int{width, height}
+	}
+}
+func (this *Engine) contentScaleCallback(win *glfw.Window, x, y float32) {
+	this.contentScale = mgl32.Vec2{x, y}
+}
+
+// ContentScale is the ratio of pixels to screen coordinates the window's
+// monitor asks for, 2 on a typical HiDPI display and 1 otherwise. Scale
+// text and other sizes given in pixels by it.
+func (this *Engine) ContentScale() (x, y float32) {
+	return this.contentScale[0], this.contentScale[1]
+}
+
+// Aspect is the width of the viewport over its height.
+func (this *Engine) Aspect() float32 {
+	return Aspect(this.viewport[0], this.viewport[1])
+}
+
+// Perspective is a projection with a vertical field of view of fovy radians
+// that fits a width by height pixel region without stretching, or the whole
+// viewport if width or height is zero.
+func (this *Engine) Perspective(fovy float32, width, height int, near, far float32) mgl32.Mat4 {
+	if width == 0 || height == 0 {
+		width, height = this.viewport[0], this.viewport[1]
+	}
+	return mgl32.Perspective(fovy, Aspect(width, height), near, far)
+}
+
+// Aspect is width over height, or 1 if either is not positive.
+func Aspect(width, height int) float32 {
+	if width <= 0 || height <= 0 {
+		return 1
+	}
+	return float32(width) / float32(height)
+}
+
+// resize brings the viewport and render targets up to the framebuffer size
+// and tells the App if it changed.
+func (this *Engine) resize() {
+	width, height := this.framebufferSize[0], this.framebufferSize[1]
+	gl.Viewport(0, 0, int32(width), int32(height))
+	if this.viewport == [2]int{width, height} {
+		return
+	}
+	this.resizeRenderTargets(width, height)
+	this.Width, this.Height = float32(width), float32(height)
+	if resizer, ok := this.App.(Resizer); ok {
+		resizer.OnResize(this, width, height)
+	}
+}
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"image"
+)
+
+const textVertexShader = `
+#version 430
+uniform mat4 projection;
+in vec2 pos;
+in vec2 uv;
+out vec2 uvf;
+void main() {
+  uvf = uv;
+  gl_Position = projection * vec4(pos, 0, 1);
+}
+`
+
+const textFragmentShader = `
+#version 430
+uniform sampler2D atlas;
+uniform vec4 color;
+in vec2 uvf;
+out vec4 outputColor;
+void main() {
+  outputColor = vec4(color.rgb, color.a * texture(atlas, uvf).a);
+}
+`
+
+// First and last runes rasterized into font atlases.
+const firstGlyph, lastGlyph = ' ', '~'
+
+// A Font is a bitmap font atlas. Every glyph occupies a cell as wide as its
+// advance and as tall as a line, so text is laid out cell by cell.
+type Font struct {
+	atlas      string
+	cells      map[rune][4]float32
+	advances   map[rune]float32
+	LineHeight float32
+}
+
+// MakeFont rasterizes the printable ASCII glyphs of face into an atlas
+// texture and registers the result as the font called name.
+func (this *Engine) MakeFont(name string, face font.Face) error {
+	metrics := face.Metrics()
+	lineHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	if lineHeight <= 0 {
+		return fmt.Errorf("font %s has no height", name)
+	}
+	const perRow = 16
+	cellWidth := 0
+	for r := rune(firstGlyph); r <= lastGlyph; r++ {
+		if advance, ok := face.GlyphAdvance(r); ok && advance.Ceil() > cellWidth {
+			cellWidth = advance.Ceil()
+		}
+	}
+	rows := (lastGlyph - firstGlyph + perRow) / perRow
+	width, height := perRow*(cellWidth+1), int(rows)*(lineHeight+1)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	drawer := font.Drawer{Dst: img, Src: image.White, Face: face}
+
+	fnt := &Font{atlas: "font." + name, cells: make(map[rune][4]float32),
+		advances: make(map[rune]float32), LineHeight: float32(lineHeight)}
+	for r := rune(firstGlyph); r <= lastGlyph; r++ {
+		advance, ok := face.GlyphAdvance(r)
+		if !ok {
+			continue
+		}
+		i := int(r - firstGlyph)
+		x, y := (i%perRow)*(cellWidth+1), (i/perRow)*(lineHeight+1)
+		drawer.Dot = fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y) + metrics.Ascent}
+		drawer.DrawString(string(r))
+		// The atlas is flipped on upload, so v runs bottom-up.
+		fnt.cells[r] = [4]float32{
+			float32(x) / float32(width), 1 - float32(y)/float32(height),
+			float32(x+advance.Ceil()) / float32(width), 1 - float32(y+lineHeight)/float32(height),
+		}
+		fnt.advances[r] = float32(advance.Ceil())
+	}
+	tex, err := this.MakeTexture(fnt.atlas, img, false)
+	if err != nil {
+		return err
+	}
+	tex.SetFilter(gl.NEAREST, gl.NEAREST)
+
+	if _, ok := this.programs["engine.text"]; !ok {
+		if err := this.MakeProgram("engine.text", map[uint32]string{
+			gl.VERTEX_SHADER:   textVertexShader,
+			gl.FRAGMENT_SHADER: textFragmentShader,
+		}); err != nil {
+			return err
+		}
+		mesh := this.MakeMesh("engine.text", "engine.text", gl.TRIANGLES)
+		mesh.SetUsage("pos", gl.STREAM_DRAW)
+		mesh.SetUsage("uv", gl.STREAM_DRAW)
+	}
+	this.fonts[name] = fnt
+	return nil
+}
+
+func (this *Engine) Font(name string) *Font {
+	return this.fonts[name]
+}
+
+// TextSize is the size in pixels text would take drawn at scale.
+func (this *Font) TextSize(text string, scale float32) (width, height float32) {
+	var line float32
+	height = this.LineHeight * scale
+	for _, r := range text {
+		if r == '\n' {
+			line = 0
+			height += this.LineHeight * scale
+			continue
+		}
+		line += this.advances[r] * scale
+		if line > width {
+			width = line
+		}
+	}
+	return width, height
+}
+
+// DrawText draws text in the font called fontName with its top left corner x
+// and y pixels from the top left of the viewport, scale pixels per atlas
+// texel. Newlines start new lines; runes without a glyph are skipped.
+func (this *Engine) DrawText(fontName, text string, x, y, scale float32, color mgl32.Vec4) error {
+	fnt, ok := this.fonts[fontName]
+	if !ok {
+		return fmt.Errorf("no font %s", fontName)
+	}
+	pos := make([]float32, 0, len(text)*12)
+	uv := make([]float32, 0, len(text)*12)
+	penX, penY := x, y
+	for _, r := range text {
+		if r == '\n' {
+			penX = x
+			penY += fnt.LineHeight * scale
+			continue
+		}
+		cell, ok := fnt.cells[r]
+		if !ok {
+			continue
+		}
+		x0, y0 := penX, penY
+		x1, y1 := penX+fnt.advances[r]*scale, penY+fnt.LineHeight*scale
+		pos = append(pos, x0, y0, x1, y0, x1, y1, x0, y0, x1, y1, x0, y1)
+		uv = append(uv, cell[0], cell[1], cell[2], cell[1], cell[2], cell[3],
+			cell[0], cell[1], cell[2], cell[3], cell[0], cell[3])
+		penX = x1
+	}
+	if len(pos) == 0 {
+		return nil
+	}
+
+	mesh := this.meshes["engine.text"]
+	if err := mesh.SetAttrib("pos", pos, 2); err != nil {
+		return err
+	}
+	if err := mesh.SetAttrib("uv", uv, 2); err != nil {
+		return err
+	}
+	proj := mgl32.Ortho2D(0, float32(this.viewport[0]), float32(this.viewport[1]), 0)
+	if err := this.UniformMatrix("engine.text", "projection", proj); err != nil {
+		return err
+	}
+	if err := this.UniformVec4("engine.text", "color", color); err != nil {
+		return err
+	}
+	if err := this.BindTexture("engine.text", "atlas", fnt.atlas, 0); err != nil {
+		return err
+	}
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	err := mesh.Draw()
+	gl.Disable(gl.BLEND)
+	gl.Enable(gl.DEPTH_TEST)
+	return err
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+	"image"
+	"image/draw"
+)
+
+// A Texture is a 2D, 2D array or cubemap texture created by the engine, with
+// the sampler object that holds its filtering and wrapping state.
+type Texture struct {
+	id            uint32
+	sampler       uint32
+	target        uint32
+	Width, Height int
+	Layers        int
+	mipmapped     bool
+}
+
+// toRGBA converts img to tightly packed, bottom-up RGBA as OpenGL expects.
+func toRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	flipped := image.NewRGBA(rgba.Bounds())
+	for y := 0; y < bounds.Dy(); y++ {
+		copy(flipped.Pix[y*flipped.Stride:(y+1)*flipped.Stride],
+			rgba.Pix[(bounds.Dy()-1-y)*rgba.Stride:(bounds.Dy()-y)*rgba.Stride])
+	}
+	return flipped
+}
+
+// imageSize is the size of img, which must be non-nil and not empty.
+func imageSize(img image.Image) (image.Point, error) {
+	if img == nil {
+		return image.Point{}, fmt.Errorf("no image")
+	}
+	size := img.Bounds().Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return image.Point{}, fmt.Errorf("image is empty")
+	}
+	return size, nil
+}
+
+func (this *Engine) newTexture(name string, target uint32, width, height, layers int, mipmaps bool) *Texture {
+	this.DeleteTexture(name)
+	tex := &Texture{target: target, Width: width, Height: height, Layers: layers, mipmapped: mipmaps}
+	gl.GenTextures(1, &tex.id)
+	gl.GenSamplers(1, &tex.sampler)
+	gl.BindTexture(target, tex.id)
+	this.textures[name] = tex
+	return tex
+}
+func (this *Texture) finish() {
+	if this.mipmapped {
+		gl.GenerateMipmap(this.target)
+		this.SetFilter(gl.LINEAR_MIPMAP_LINEAR, gl.LINEAR)
+	} else {
+		gl.TexParameteri(this.target, gl.TEXTURE_MAX_LEVEL, 0)
+		this.SetFilter(gl.LINEAR, gl.LINEAR)
+	}
+	this.SetWrap(gl.CLAMP_TO_EDGE)
+}
+
+// MakeTexture creates the 2D texture called name from img, replacing any
+// texture of the same name.
+func (this *Engine) MakeTexture(name string, img image.Image, mipmaps bool) (*Texture, error) {
+	if _, err := imageSize(img); err != nil {
+		return nil, fmt.Errorf("texture %s: %v", name, err)
+	}
+	rgba := toRGBA(img)
+	size := rgba.Bounds().Size()
+	tex := this.newTexture(name, gl.TEXTURE_2D, size.X, size.Y, 1, mipmaps)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(size.X), int32(size.Y), 0,
+		gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	tex.finish()
+	return tex, nil
+}
+
+// MakeTextureArray creates the 2D array texture called name with one layer
+// per image. All images must be the same size.
+func (this *Engine) MakeTextureArray(name string, imgs []image.Image, mipmaps bool) (*Texture, error) {
+	if len(imgs) == 0 {
+		return nil, fmt.Errorf("texture array %s has no layers", name)
+	}
+	size, err := imageSize(imgs[0])
+	if err != nil {
+		return nil, fmt.Errorf("layer 0 of texture array %s: %v", name, err)
+	}
+	for i, img := range imgs {
+		if img == nil {
+			return nil, fmt.Errorf("layer %d of texture array %s: no image", i, name)
+		}
+		if img.Bounds().Size() != size {
+			return nil, fmt.Errorf("layer %d of texture array %s is %v, not %v", i, name, img.Bounds().Size(), size)
+		}
+	}
+	tex := this.newTexture(name, gl.TEXTURE_2D_ARRAY, size.X, size.Y, len(imgs), mipmaps)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexImage3D(gl.TEXTURE_2D_ARRAY, 0, gl.RGBA8, int32(size.X), int32(size.Y), int32(len(imgs)), 0,
+		gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	for i, img := range imgs {
+		gl.TexSubImage3D(gl.TEXTURE_2D_ARRAY, 0, 0, 0, int32(i), int32(size.X), int32(size.Y), 1,
+			gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(toRGBA(img).Pix))
+	}
+	tex.finish()
+	return tex, nil
+}
+
+// MakeCubemap creates the cubemap texture called name from its faces, in the
+// order +X, -X, +Y, -Y, +Z, -Z. All faces must be the same square size.
+func (this *Engine) MakeCubemap(name string, faces [6]image.Image, mipmaps bool) (*Texture, error) {
+	size, err := imageSize(faces[0])
+	if err != nil {
+		return nil, fmt.Errorf("face 0 of cubemap %s: %v", name, err)
+	}
+	for i, face := range faces {
+		if face == nil {
+			return nil, fmt.Errorf("face %d of cubemap %s: no image", i, name)
+		}
+		if face.Bounds().Size() != size || size.X != size.Y {
+			return nil, fmt.Errorf("face %d of cubemap %s is %v, not square and %v", i, name, face.Bounds().Size(), size)
+		}
+	}
+	tex := this.newTexture(name, gl.TEXTURE_CUBE_MAP, size.X, size.Y, 6, mipmaps)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for i, face := range faces {
+		// Cubemap faces are addressed top-down, so skip toRGBA's flip.
+		rgba := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+		draw.Draw(rgba, rgba.Bounds(), face, face.Bounds().Min, draw.Src)
+		gl.TexImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+uint32(i), 0, gl.RGBA8, int32(size.X), int32(size.Y), 0,
+			gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	}
+	tex.finish()
+	return tex, nil
+}
+
+// SetFilter sets the minifying and magnifying filters, e.g. gl.LINEAR_MIPMAP_LINEAR and gl.LINEAR.
+func (this *Texture) SetFilter(min, mag int32) {
+	gl.SamplerParameteri(this.sampler, gl.TEXTURE_MIN_FILTER, min)
+	gl.SamplerParameteri(this.sampler, gl.TEXTURE_MAG_FILTER, mag)
+}
+
+// SetWrap sets the wrap mode, e.g. gl.REPEAT or gl.CLAMP_TO_EDGE, along every axis.
+func (this *Texture) SetWrap(wrap int32) {
+	gl.SamplerParameteri(this.sampler, gl.TEXTURE_WRAP_S, wrap)
+	gl.SamplerParameteri(this.sampler, gl.TEXTURE_WRAP_T, wrap)
+	gl.SamplerParameteri(this.sampler, gl.TEXTURE_WRAP_R, wrap)
+}
+
+func (this *Engine) Texture(name string) *Texture {
+	return this.textures[name]
+}
+
+// BindTexture binds the texture called texture and its sampler state to
+// texture unit unit and points the sampler uniform in program at it.
+func (this *Engine) BindTexture(program, uniform, texture string, unit uint32) error {
+	tex, ok := this.textures[texture]
+	if !ok {
+		return fmt.Errorf("no texture %s", texture)
+	}
+	if err := this.UniformSampler(program, uniform, int32(unit)); err != nil {
+		return err
+	}
+	tex.bind(unit)
+	return nil
+}
+func (this *Texture) bind(unit uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(this.target, this.id)
+	gl.BindSampler(unit, this.sampler)
+}
+
+// DeleteTexture frees the texture called name and its sampler.
+func (this *Engine) DeleteTexture(name string) {
+	if tex, ok := this.textures[name]; ok {
+		gl.DeleteTextures(1, &tex.id)
+		gl.DeleteSamplers(1, &tex.sampler)
+		delete(this.textures, name)
+	}
+}
@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"strings"
+)
+
+// A variable is an active uniform or attribute found by introspecting a
+// linked program. Arrays are recorded once, under their name without "[0]".
+type variable struct {
+	location int32
+	xtype    uint32
+	size     int32
+}
+
+var typeNames = map[uint32]string{
+	gl.FLOAT:                         "float",
+	gl.FLOAT_VEC2:                    "vec2",
+	gl.FLOAT_VEC3:                    "vec3",
+	gl.FLOAT_VEC4:                    "vec4",
+	gl.INT:                           "int",
+	gl.INT_VEC2:                      "ivec2",
+	gl.INT_VEC3:                      "ivec3",
+	gl.INT_VEC4:                      "ivec4",
+	gl.UNSIGNED_INT:                  "uint",
+	gl.BOOL:                          "bool",
+	gl.BOOL_VEC2:                     "bvec2",
+	gl.BOOL_VEC3:                     "bvec3",
+	gl.BOOL_VEC4:                     "bvec4",
+	gl.FLOAT_MAT2:                    "mat2",
+	gl.FLOAT_MAT3:                    "mat3",
+	gl.FLOAT_MAT4:                    "mat4",
+	gl.SAMPLER_1D:                    "sampler1D",
+	gl.SAMPLER_2D:                    "sampler2D",
+	gl.SAMPLER_3D:                    "sampler3D",
+	gl.SAMPLER_CUBE:                  "samplerCube",
+	gl.SAMPLER_1D_ARRAY:              "sampler1DArray",
+	gl.SAMPLER_2D_ARRAY:              "sampler2DArray",
+	gl.SAMPLER_2D_SHADOW:             "sampler2DShadow",
+	gl.SAMPLER_2D_ARRAY_SHADOW:       "sampler2DArrayShadow",
+	gl.SAMPLER_CUBE_SHADOW:           "samplerCubeShadow",
+	gl.SAMPLER_2D_MULTISAMPLE:        "sampler2DMS",
+	gl.SAMPLER_BUFFER:                "samplerBuffer",
+	gl.INT_SAMPLER_2D:                "isampler2D",
+	gl.INT_SAMPLER_3D:                "isampler3D",
+	gl.INT_SAMPLER_2D_ARRAY:          "isampler2DArray",
+	gl.UNSIGNED_INT_SAMPLER_2D:       "usampler2D",
+	gl.UNSIGNED_INT_SAMPLER_3D:       "usampler3D",
+	gl.UNSIGNED_INT_SAMPLER_2D_ARRAY: "usampler2DArray",
+}
+
+var samplerTypes = []uint32{
+	gl.SAMPLER_1D, gl.SAMPLER_2D, gl.SAMPLER_3D, gl.SAMPLER_CUBE,
+	gl.SAMPLER_1D_ARRAY, gl.SAMPLER_2D_ARRAY, gl.SAMPLER_2D_SHADOW,
+	gl.SAMPLER_2D_ARRAY_SHADOW, gl.SAMPLER_CUBE_SHADOW, gl.SAMPLER_2D_MULTISAMPLE,
+	gl.SAMPLER_BUFFER, gl.INT_SAMPLER_2D, gl.INT_SAMPLER_3D, gl.INT_SAMPLER_2D_ARRAY,
+	gl.UNSIGNED_INT_SAMPLER_2D, gl.UNSIGNED_INT_SAMPLER_3D, gl.UNSIGNED_INT_SAMPLER_2D_ARRAY,
+}
+
+func typeName(xtype uint32) string {
+	if name, ok := typeNames[xtype]; ok {
+		return name
+	}
+	return fmt.Sprintf("type 0x%x", xtype)
+}
+
+// introspect lists the active uniforms or attributes of a linked program.
+// Variables without a location (block members, built-ins) are skipped.
+func introspect(program, countParam, lengthParam uint32,
+	active func(uint32, uint32, int32, *int32, *int32, *uint32, *uint8),
+	location func(uint32, *uint8) int32) map[string]variable {
+	vars := make(map[string]variable)
+	var count, maxLength int32
+	gl.GetProgramiv(program, countParam, &count)
+	gl.GetProgramiv(program, lengthParam, &maxLength)
+	if maxLength == 0 {
+		return vars
+	}
+	buf := make([]uint8, maxLength)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length, size int32
+		var xtype uint32
+		active(program, i, maxLength, &length, &size, &xtype, &buf[0])
+		name := string(buf[:length])
+		loc := location(program, gl.Str(name+"\x00"))
+		if loc < 0 {
+			continue
+		}
+		vars[strings.TrimSuffix(name, "[0]")] = variable{location: loc, xtype: xtype, size: size}
+	}
+	return vars
+}
+
+func (this *Engine) lookup(vars map[string](map[string]variable), kind, program, name string) (variable, error) {
+	progVars, ok := vars[program]
+	if !ok {
+		return variable{}, fmt.Errorf("no program %s", program)
+	}
+	v, ok := progVars[name]
+	if !ok {
+		return variable{}, fmt.Errorf("program %s has no active %s %s", program, kind, name)
+	}
+	return v, nil
+}
+
+// getLoc looks up uniform in program, checks that it has one of the given
+// types and room for count elements, and makes program current.
+func (this *Engine) getLoc(program, uniform string, count int, types ...uint32) (int32, error) {
+	v, err := this.lookup(this.uniforms, "uniform", program, uniform)
+	if err != nil {
+		return -1, err
+	}
+	match := false
+	for _, t := range types {
+		if v.xtype == t {
+			match = true
+		}
+	}
+	if !match {
+		return -1, fmt.Errorf("uniform %s in program %s is a %s, not a %s",
+			uniform, program, typeName(v.xtype), typeName(types[0]))
+	}
+	if count > int(v.size) {
+		return -1, fmt.Errorf("uniform %s in program %s holds %d elements, got %d",
+			uniform, program, v.size, count)
+	}
+	this.UseProgram(program)
+	return v.location, nil
+}
+func (this *Engine) getAttrib(program, attrib string) (uint32, error) {
+	v, err := this.lookup(this.attribs, "attribute", program, attrib)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(v.location), nil
+}
+
+// Uniforms returns the names of the active uniforms of program mapped to their GLSL types.
+func (this *Engine) Uniforms(program string) map[string]string {
+	ans := make(map[string]string)
+	for name, v := range this.uniforms[program] {
+		ans[name] = typeName(v.xtype)
+	}
+	return ans
+}
+
+// Attributes returns the names of the active attributes of program mapped to their GLSL types.
+func (this *Engine) Attributes(program string) map[string]string {
+	ans := make(map[string]string)
+	for name, v := range this.attribs[program] {
+		ans[name] = typeName(v.xtype)
+	}
+	return ans
+}
+
+func (this *Engine) UniformMatrix(program, uniform string, matrix mgl32.Mat4) error {
+	uni, err := this.getLoc(program, uniform, 1, gl.FLOAT_MAT4)
+	if err != nil {
+		return err
+	}
+	gl.UniformMatrix4fv(uni, 1, false, &matrix[0])
+	return nil
+}
+func (this *Engine) UniformMatrix3(program, uniform string, matrix mgl32.Mat3) error {
+	uni, err := this.getLoc(program, uniform, 1, gl.FLOAT_MAT3)
+	if err != nil {
+		return err
+	}
+	gl.UniformMatrix3fv(uni, 1, false, &matrix[0])
+	return nil
+}
+func (this *Engine) UniformFloat(program, uniform string, float float32) error {
+	uni, err := this.getLoc(program, uniform, 1, gl.FLOAT)
+	if err != nil {
+		return err
+	}
+	gl.Uniform1f(uni, float)
+	return nil
+}
+func (this *Engine) UniformInt(program, uniform string, i int32) error {
+	uni, err := this.getLoc(program, uniform, 1, gl.INT, gl.BOOL)
+	if err != nil {
+		return err
+	}
+	gl.Uniform1i(uni, i)
+	return nil
+}
+func (this *Engine) UniformVec2(program, uniform string, vec mgl32.Vec2) error {
+	uni, err := this.getLoc(program, uniform, 1, gl.FLOAT_VEC2)
+	if err != nil {
+		return err
+	}
+	gl.Uniform2f(uni, vec[0], vec[1])
+	return nil
+}
+func (this *Engine) UniformVec3(program, uniform string, vec mgl32.Vec3) error {
+	uni, err := this.getLoc(program, uniform, 1, gl.FLOAT_VEC3)
+	if err != nil {
+		return err
+	}
+	gl.Uniform3f(uni, vec[0], vec[1], vec[2])
+	return nil
+}
+func (this *Engine) UniformVec4(program, uniform string, vec mgl32.Vec4) error {
+	uni, err := this.getLoc(program, uniform, 1, gl.FLOAT_VEC4)
+	if err != nil {
+		return err
+	}
+	gl.Uniform4f(uni, vec[0], vec[1], vec[2], vec[3])
+	return nil
+}
+
+// UniformSampler points the sampler uniform at texture unit unit.
+func (this *Engine) UniformSampler(program, uniform string, unit int32) error {
+	uni, err := this.getLoc(program, uniform, 1, samplerTypes...)
+	if err != nil {
+		return err
+	}
+	gl.Uniform1i(uni, unit)
+	return nil
+}
+
+func (this *Engine) UniformFloats(program, uniform string, arr []float32) error {
+	uni, err := this.getLoc(program, uniform, len(arr), gl.FLOAT)
+	if err != nil {
+		return err
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	gl.Uniform1fv(uni, int32(len(arr)), &arr[0])
+	return nil
+}
+func (this *Engine) UniformInts(program, uniform string, arr []int32) error {
+	uni, err := this.getLoc(program, uniform, len(arr), gl.INT, gl.BOOL)
+	if err != nil {
+		return err
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	gl.Uniform1iv(uni, int32(len(arr)), &arr[0])
+	return nil
+}
+
+// UniformVecs uploads a flat array of vec2s, vec3s or vec4s, depending on the
+// declared type of uniform.
+func (this *Engine) UniformVecs(program, uniform string, arr []float32) error {
+	v, err := this.lookup(this.uniforms, "uniform", program, uniform)
+	if err != nil {
+		return err
+	}
+	if len(arr) == 0 {
+		return nil
+	}
+	var width int
+	switch v.xtype {
+	case gl.FLOAT_VEC2:
+		width = 2
+	case gl.FLOAT_VEC3:
+		width = 3
+	case gl.FLOAT_VEC4:
+		width = 4
+	default:
+		return fmt.Errorf("uniform %s in program %s is a %s, not a vector", uniform, program, typeName(v.xtype))
+	}
+	if len(arr)%width != 0 {
+		return fmt.Errorf("%d floats do not make whole %ss for uniform %s", len(arr), typeName(v.xtype), uniform)
+	}
+	uni, err := this.getLoc(program, uniform, len(arr)/width, v.xtype)
+	if err != nil {
+		return err
+	}
+	switch width {
+	case 2:
+		gl.Uniform2fv(uni, int32(len(arr)/2), &arr[0])
+	case 3:
+		gl.Uniform3fv(uni, int32(len(arr)/3), &arr[0])
+	case 4:
+		gl.Uniform4fv(uni, int32(len(arr)/4), &arr[0])
+	}
+	return nil
+}
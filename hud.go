@@ -0,0 +1,148 @@
+package main
+
+import (
+	"./engine"
+	"./input"
+	"bytes"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/image/font/basicfont"
+)
+
+const hudScale = 2
+const hudMargin = 8
+
+var modeNames = []string{"free flight", "velocity hold", "position hold"}
+
+type hud struct {
+	frames  int
+	elapsed float32
+	fps     float32
+	// Whether the input debug overlay is shown.
+	debug bool
+	// Controller connections and disconnections, shown for noticeTime
+	// seconds each.
+	notices []notice
+}
+
+type notice struct {
+	text string
+	left float32
+}
+
+const noticeTime = 4
+
+func (this *hud) init(engine *engine.Engine) {
+	if err := engine.MakeFont("hud", basicfont.Face7x13); err != nil {
+		panic(err)
+	}
+}
+
+// notify shows text for a while.
+func (this *hud) notify(text string) {
+	this.notices = append(this.notices, notice{text: text, left: noticeTime})
+}
+
+// tick counts a frame of delta seconds, updating the FPS twice a second and
+// expiring notices.
+func (this *hud) tick(delta float32) {
+	kept := this.notices[:0]
+	for _, n := range this.notices {
+		if n.left -= delta; n.left > 0 {
+			kept = append(kept, n)
+		}
+	}
+	this.notices = kept
+	this.frames++
+	this.elapsed += delta
+	if this.elapsed >= 0.5 {
+		this.fps = float32(this.frames) / this.elapsed
+		this.frames = 0
+		this.elapsed = 0
+	}
+}
+
+// modeName names the control mode of ship.
+func modeName(ship *BHShip) string {
+	if ship.mode >= 0 && ship.mode < len(modeNames) {
+		return modeNames[ship.mode]
+	}
+	return "unknown"
+}
+
+func (this *hud) draw(engine *engine.Engine, app *mainApp, in *input.Input) {
+	ship := &app.players[0].ship
+	text := fmt.Sprintf("FPS %.0f\nstep %d\nstars %d\nspeed %.4f\nmode %s\nseed %d",
+		this.fps, app.step, numStars, ship.velocity.Len(), modeName(ship), app.seed)
+	if len(app.players) > 1 {
+		text += fmt.Sprintf("\nplayers %d", len(app.players))
+	}
+	if app.paused {
+		text += "\nPAUSED"
+	}
+	if engine.Playback() != nil {
+		text += "\nREPLAY"
+	}
+	if err := engine.DrawText("hud", text, hudMargin, hudMargin, hudScale, mgl32.Vec4{1, 1, 1, 0.8}); err != nil {
+		panic(err)
+	}
+	this.drawPlayers(engine, app, in)
+	this.drawNotices(engine)
+	if this.debug {
+		_, height := engine.Font("hud").TextSize(text, hudScale)
+		if err := engine.DrawText("hud", engine.FrameStats().String()+debugString(in), hudMargin, hudMargin*2+height, 1, mgl32.Vec4{0.6, 1, 0.6, 0.9}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// drawPlayers labels the region of each player after the first with their
+// controller, speed and mode.
+func (this *hud) drawPlayers(engine *engine.Engine, app *mainApp, in *input.Input) {
+	width, height := engine.Viewport()
+	for i, region := range regions(len(app.players), width, height) {
+		if i == 0 {
+			continue
+		}
+		p := app.players[i]
+		text := fmt.Sprintf("P%d\nspeed %.4f\nmode %s", p.pad+1, p.ship.velocity.Len(), modeName(&p.ship))
+		if !in.GamePads[p.pad].Active {
+			text += "\ncontroller disconnected"
+		}
+		x, y := float32(region[0])+hudMargin, float32(int32(height)-region[1]-region[3])+hudMargin
+		if err := engine.DrawText("hud", text, x, y, hudScale, mgl32.Vec4{1, 1, 1, 0.8}); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// drawNotices lists the notices along the bottom of the window, newest last.
+func (this *hud) drawNotices(engine *engine.Engine) {
+	_, height := engine.Viewport()
+	fnt := engine.Font("hud")
+	y := float32(height) - hudMargin - fnt.LineHeight*hudScale*float32(len(this.notices))
+	for _, n := range this.notices {
+		alpha := n.left
+		if alpha > 1 {
+			alpha = 1
+		}
+		if err := engine.DrawText("hud", n.text, hudMargin, y, hudScale, mgl32.Vec4{1, 0.8, 0.4, alpha}); err != nil {
+			panic(err)
+		}
+		y += fnt.LineHeight * hudScale
+	}
+}
+
+// debugString describes the live input state: mouse, controllers and the
+// remaps set on each controller.
+func debugString(in *input.Input) string {
+	var buf bytes.Buffer
+	buf.WriteString(in.Mouse.String())
+	buf.WriteString(in.String())
+	for i := range in.GamePads {
+		if remap := in.GamePads[i].RemapString(); remap != "" {
+			buf.WriteString(fmt.Sprintf("\nController %d remap\n%s", i, remap))
+		}
+	}
+	return buf.String()
+}
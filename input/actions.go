@@ -0,0 +1,418 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of Source.
+const (
+	SourceKey = iota
+	SourceMouseButton
+	SourceMouseAxis
+	SourcePadButton
+	SourcePadAxis
+)
+
+// Mouse axes, the codes of SourceMouseAxis sources.
+const (
+	MouseX = iota
+	MouseY
+	ScrollX
+	ScrollY
+	MouseLookX
+	MouseLookY
+)
+
+// Gamepad axes, the codes of SourcePadAxis sources.
+const (
+	PadLeftX = iota
+	PadLeftY
+	PadRightX
+	PadRightY
+	PadLeftTrigger
+	PadRightTrigger
+	PadDpadX
+	PadDpadY
+)
+
+// A Source is one input an action is bound to. Code is a glfw.Key, a
+// glfw.MouseButton, a mouse axis, a gamepad button bit or a gamepad axis,
+// depending on Kind. Its value, 0 to 1 for buttons, is multiplied by Scale.
+type Source struct {
+	Kind  int
+	Code  int
+	Scale float32
+}
+
+var keyNames = map[string]glfw.Key{
+	"Space": glfw.KeySpace, "Apostrophe": glfw.KeyApostrophe, "Comma": glfw.KeyComma,
+	"Minus": glfw.KeyMinus, "Period": glfw.KeyPeriod, "Slash": glfw.KeySlash,
+	"Semicolon": glfw.KeySemicolon, "Equal": glfw.KeyEqual, "LeftBracket": glfw.KeyLeftBracket,
+	"Backslash": glfw.KeyBackslash, "RightBracket": glfw.KeyRightBracket, "GraveAccent": glfw.KeyGraveAccent,
+	"Escape": glfw.KeyEscape, "Enter": glfw.KeyEnter, "Tab": glfw.KeyTab, "Backspace": glfw.KeyBackspace,
+	"Insert": glfw.KeyInsert, "Delete": glfw.KeyDelete, "Right": glfw.KeyRight, "Left": glfw.KeyLeft,
+	"Down": glfw.KeyDown, "Up": glfw.KeyUp, "PageUp": glfw.KeyPageUp, "PageDown": glfw.KeyPageDown,
+	"Home": glfw.KeyHome, "End": glfw.KeyEnd, "KPEnter": glfw.KeyKPEnter,
+	"LeftShift": glfw.KeyLeftShift, "LeftControl": glfw.KeyLeftControl, "LeftAlt": glfw.KeyLeftAlt,
+	"RightShift": glfw.KeyRightShift, "RightControl": glfw.KeyRightControl, "RightAlt": glfw.KeyRightAlt,
+}
+var mouseButtonNames = map[string]glfw.MouseButton{
+	"left": glfw.MouseButtonLeft, "right": glfw.MouseButtonRight, "middle": glfw.MouseButtonMiddle,
+}
+var mouseAxisNames = map[string]int{"x": MouseX, "y": MouseY, "scrollx": ScrollX, "scrolly": ScrollY,
+	"lookx": MouseLookX, "looky": MouseLookY}
+var padAxisNames = map[string]int{
+	"leftx": PadLeftX, "lefty": PadLeftY, "rightx": PadRightX, "righty": PadRightY,
+	"lt": PadLeftTrigger, "rt": PadRightTrigger, "dpadx": PadDpadX, "dpady": PadDpadY,
+}
+
+func init() {
+	for c := 'A'; c <= 'Z'; c++ {
+		keyNames[string(c)] = glfw.KeyA + glfw.Key(c-'A')
+	}
+	for c := '0'; c <= '9'; c++ {
+		keyNames[string(c)] = glfw.Key0 + glfw.Key(c-'0')
+	}
+	for i := 1; i <= 12; i++ {
+		keyNames[fmt.Sprintf("F%d", i)] = glfw.KeyF1 + glfw.Key(i-1)
+	}
+}
+
+// ParseSource parses a source written as kind:name, optionally followed by
+// *scale: key:W, mousebutton:left, mouse:x, pad:A or axis:lefty*-1.
+func ParseSource(text string) (Source, error) {
+	source := Source{Scale: 1}
+	if star := strings.Index(text, "*"); star >= 0 {
+		scale, err := strconv.ParseFloat(text[star+1:], 32)
+		if err != nil {
+			return source, fmt.Errorf("bad scale in %q: %v", text, err)
+		}
+		source.Scale = float32(scale)
+		text = text[:star]
+	}
+	colon := strings.Index(text, ":")
+	if colon < 0 {
+		return source, fmt.Errorf("source %q is not kind:name", text)
+	}
+	kind, name := text[:colon], text[colon+1:]
+	ok := false
+	switch kind {
+	case "key":
+		var key glfw.Key
+		key, ok = keyNames[name]
+		source.Kind, source.Code = SourceKey, int(key)
+	case "mousebutton":
+		var button glfw.MouseButton
+		button, ok = mouseButtonNames[name]
+		source.Kind, source.Code = SourceMouseButton, int(button)
+	case "mouse":
+		source.Kind = SourceMouseAxis
+		source.Code, ok = mouseAxisNames[name]
+	case "pad":
+		source.Kind = SourcePadButton
+		for _, names := range []map[uint32]string{buttonNames, virtualButtonNames} {
+			for bit, buttonName := range names {
+				if buttonName == name {
+					source.Code, ok = int(bit), true
+				}
+			}
+		}
+	case "axis":
+		source.Kind = SourcePadAxis
+		source.Code, ok = padAxisNames[name]
+	default:
+		return source, fmt.Errorf("unknown source kind %q in %q", kind, text)
+	}
+	if !ok {
+		return source, fmt.Errorf("unknown %s %q", kind, name)
+	}
+	return source, nil
+}
+
+func (this Source) String() string {
+	var text string
+	switch this.Kind {
+	case SourceKey:
+		text = "key:" + keyName(glfw.Key(this.Code))
+	case SourceMouseButton:
+		text = "mousebutton:" + mouseButtonName(glfw.MouseButton(this.Code))
+	case SourceMouseAxis:
+		text = "mouse:" + axisName(mouseAxisNames, this.Code)
+	case SourcePadButton:
+		text = "pad:" + buttonName(uint32(this.Code))
+	case SourcePadAxis:
+		text = "axis:" + axisName(padAxisNames, this.Code)
+	}
+	if this.Scale != 1 {
+		text += "*" + strconv.FormatFloat(float64(this.Scale), 'g', -1, 32)
+	}
+	return text
+}
+func buttonName(bit uint32) string {
+	if name, ok := buttonNames[bit]; ok {
+		return name
+	}
+	return virtualButtonNames[bit]
+}
+func keyName(key glfw.Key) string {
+	for name, k := range keyNames {
+		if k == key {
+			return name
+		}
+	}
+	return fmt.Sprint(int(key))
+}
+func mouseButtonName(button glfw.MouseButton) string {
+	for name, b := range mouseButtonNames {
+		if b == button {
+			return name
+		}
+	}
+	return fmt.Sprint(int(button))
+}
+func axisName(names map[string]int, axis int) string {
+	for name, a := range names {
+		if a == axis {
+			return name
+		}
+	}
+	return fmt.Sprint(axis)
+}
+
+// Value reads the source from in, with keys reporting whether a key is held.
+func (this Source) Value(in *Input, pad int, keys func(glfw.Key) bool) float32 {
+	var value float32
+	switch this.Kind {
+	case SourceKey:
+		if keys != nil && keys(glfw.Key(this.Code)) {
+			value = 1
+		}
+	case SourceMouseButton:
+		switch glfw.MouseButton(this.Code) {
+		case glfw.MouseButtonLeft:
+			value = boolValue(in.Mouse.Left)
+		case glfw.MouseButtonRight:
+			value = boolValue(in.Mouse.Right)
+		case glfw.MouseButtonMiddle:
+			value = boolValue(in.Mouse.Middle)
+		}
+	case SourceMouseAxis:
+		value = [...]float32{in.Mouse.Delta.X(), in.Mouse.Delta.Y(), in.Mouse.Scroll.X(), in.Mouse.Scroll.Y(),
+			in.Mouse.Look.X(), in.Mouse.Look.Y()}[this.Code]
+	case SourcePadButton, SourcePadAxis:
+		if pad < 0 || pad >= len(in.GamePads) || !in.GamePads[pad].Active {
+			return 0
+		}
+		gp := &in.GamePads[pad]
+		if this.Kind == SourcePadButton {
+			value = boolValue(gp.Held(uint32(this.Code)))
+		} else {
+			value = [...]float32{gp.LeftStick.X(), gp.LeftStick.Y(), gp.RightStick.X(), gp.RightStick.Y(),
+				gp.LeftTrigger, gp.RightTrigger, gp.Dpad.X(), gp.Dpad.Y()}[this.Code]
+		}
+	}
+	return value * this.Scale
+}
+func boolValue(b bool) float32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Actions maps named actions and axes, such as "thrust_forward" or "roll", to
+// any number of sources. The value of an action is the sum of its sources.
+type Actions struct {
+	// Pad is the gamepad that pad sources read.
+	Pad      int
+	bindings map[string][]Source
+	order    []string
+	values   map[string]float32
+	last     map[string]float32
+}
+
+func NewActions() *Actions {
+	return &Actions{bindings: make(map[string][]Source),
+		values: make(map[string]float32), last: make(map[string]float32)}
+}
+
+// Bind adds sources to action.
+func (this *Actions) Bind(action string, sources ...Source) {
+	if _, ok := this.bindings[action]; !ok {
+		this.order = append(this.order, action)
+	}
+	this.bindings[action] = append(this.bindings[action], sources...)
+}
+
+// Rebind replaces the sources of action that come from the same kind of
+// device as source, keyboard and mouse or gamepad, with source.
+func (this *Actions) Rebind(action string, source Source) {
+	padSource := source.Kind == SourcePadButton || source.Kind == SourcePadAxis
+	kept := []Source{}
+	for _, old := range this.bindings[action] {
+		if (old.Kind == SourcePadButton || old.Kind == SourcePadAxis) != padSource {
+			kept = append(kept, old)
+		}
+	}
+	if _, ok := this.bindings[action]; !ok {
+		this.order = append(this.order, action)
+	}
+	this.bindings[action] = append(kept, source)
+}
+
+// Unbind removes every source from action.
+func (this *Actions) Unbind(action string) {
+	this.bindings[action] = nil
+}
+func (this *Actions) Bindings(action string) []Source {
+	return this.bindings[action]
+}
+
+// Names lists the actions in the order they were first bound.
+func (this *Actions) Names() []string {
+	return this.order
+}
+
+// Update reads every action from in, with keys reporting whether a key is
+// held. Call it once per tick.
+func (this *Actions) Update(in *Input, keys func(glfw.Key) bool) {
+	this.last, this.values = this.values, this.last
+	for action, sources := range this.bindings {
+		var value float32
+		for _, source := range sources {
+			value += source.Value(in, this.Pad, keys)
+		}
+		this.values[action] = value
+	}
+}
+
+// Value is the summed value of the sources of action.
+func (this *Actions) Value(action string) float32 {
+	return this.values[action]
+}
+
+// Held reports whether action's value is at least one half.
+func (this *Actions) Held(action string) bool {
+	return this.values[action] >= 0.5
+}
+
+// Pressed reports whether action became held this tick.
+func (this *Actions) Pressed(action string) bool {
+	return this.values[action] >= 0.5 && this.last[action] < 0.5
+}
+
+// Parse reads bindings, one action per line written as
+// "action = source source ...". Blank lines and lines starting with # are
+// skipped. Actions read replace any earlier bindings of the same name.
+func (this *Actions) Parse(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		eq := strings.Index(text, "=")
+		if eq < 0 {
+			return fmt.Errorf("line %d: expected action = sources", line)
+		}
+		action := strings.TrimSpace(text[:eq])
+		sources := []Source{}
+		for _, field := range strings.Fields(text[eq+1:]) {
+			source, err := ParseSource(field)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", line, err)
+			}
+			sources = append(sources, source)
+		}
+		this.Unbind(action)
+		this.Bind(action, sources...)
+	}
+	return scanner.Err()
+}
+
+// Load reads bindings from the file at path with Parse.
+func (this *Actions) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return this.Parse(file)
+}
+
+func (this *Actions) String() string {
+	var buf bytes.Buffer
+	for _, action := range this.order {
+		buf.WriteString(action + " =")
+		for _, source := range this.bindings[action] {
+			buf.WriteString(" " + source.String())
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// Save writes the bindings to the file at path in the form Parse reads.
+func (this *Actions) Save(path string) error {
+	return ioutil.WriteFile(path, []byte(this.String()), 0644)
+}
+
+// Capture finds a source that is active right now, for binding menus that
+// wait for the player to press what they want: a held key or button, a mouse
+// movement or an axis pushed most of the way. Axes are returned with the
+// sign they were pushed in as their scale.
+func Capture(in *Input, pad int, keys func(glfw.Key) bool) (Source, bool) {
+	names := make([]string, 0, len(keyNames))
+	for name := range keyNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	candidates := []Source{}
+	for _, name := range names {
+		candidates = append(candidates, Source{Kind: SourceKey, Code: int(keyNames[name]), Scale: 1})
+	}
+	for _, button := range mouseButtonNames {
+		candidates = append(candidates, Source{Kind: SourceMouseButton, Code: int(button), Scale: 1})
+	}
+	// Virtual buttons are left out, so triggers and sticks bind as axes.
+	for bit := range buttonNames {
+		candidates = append(candidates, Source{Kind: SourcePadButton, Code: int(bit), Scale: 1})
+	}
+	for _, source := range candidates {
+		if source.Value(in, pad, keys) >= 0.5 {
+			return source, true
+		}
+	}
+	const axisThreshold = 0.7
+	for _, axis := range []int{MouseLookX, MouseLookY, ScrollX, ScrollY} {
+		source := Source{Kind: SourceMouseAxis, Code: axis, Scale: 1}
+		if v := source.Value(in, pad, keys); v >= axisThreshold || v <= -axisThreshold ||
+			(axis == ScrollX || axis == ScrollY) && v != 0 {
+			source.Scale = sign(v)
+			return source, true
+		}
+	}
+	for axis := PadLeftX; axis <= PadDpadY; axis++ {
+		source := Source{Kind: SourcePadAxis, Code: axis, Scale: 1}
+		if v := source.Value(in, pad, keys); v >= axisThreshold || v <= -axisThreshold {
+			source.Scale = sign(v)
+			return source, true
+		}
+	}
+	return Source{}, false
+}
+func sign(v float32) float32 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
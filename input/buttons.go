@@ -0,0 +1,162 @@
+package input
+
+import (
+	"math/bits"
+)
+
+// Virtual buttons, held while a trigger or stick is pushed past its
+// threshold. They follow the real button bits, so both are queried alike.
+const (
+	ButtonLT = 1 << (16 + iota)
+	ButtonRT
+	ButtonLStickUp
+	ButtonLStickDown
+	ButtonLStickLeft
+	ButtonLStickRight
+	ButtonRStickUp
+	ButtonRStickDown
+	ButtonRStickLeft
+	ButtonRStickRight
+)
+
+const numButtons = 26
+
+var virtualButtonNames = map[uint32]string{
+	ButtonLT: "LT", ButtonRT: "RT",
+	ButtonLStickUp: "LStickUp", ButtonLStickDown: "LStickDown",
+	ButtonLStickLeft: "LStickLeft", ButtonLStickRight: "LStickRight",
+	ButtonRStickUp: "RStickUp", ButtonRStickDown: "RStickDown",
+	ButtonRStickLeft: "RStickLeft", ButtonRStickRight: "RStickRight",
+}
+
+// The state of one button on one tick.
+type ButtonState struct {
+	Down bool
+	// Whether the button went down or came up since the last tick.
+	Pressed, Released bool
+	// Whether this press came within the pad's double tap time of the last.
+	DoubleTapped bool
+	// How long the button has been down, or was down if it was just released.
+	HeldFor float32
+	// Time since the button was last pressed, and whether that press may be
+	// the first of a double tap.
+	sinceTap   float32
+	tapPending bool
+}
+
+// A Threshold turns an analog value into a digital button with hysteresis:
+// the button goes down once the value reaches Press and comes back up once
+// it drops below Release.
+type Threshold struct {
+	Press, Release float32
+}
+
+func (this Threshold) apply(value float32, down bool) bool {
+	if down {
+		return value >= this.Release
+	}
+	return value >= this.Press
+}
+
+var DefaultThreshold = Threshold{Press: 0.5, Release: 0.4}
+
+// DefaultDoubleTapTime is the longest gap in seconds between the presses of a
+// double tap that pads start with.
+const DefaultDoubleTapTime = 0.3
+
+// State is the state of button, any of the Button constants.
+func (this *GamePad) State(button uint32) ButtonState {
+	i := bits.TrailingZeros32(button)
+	if i >= numButtons {
+		return ButtonState{}
+	}
+	return this.States[i]
+}
+func (this *GamePad) Held(button uint32) bool {
+	return this.State(button).Down
+}
+func (this *GamePad) Pressed(button uint32) bool {
+	return this.State(button).Pressed
+}
+func (this *GamePad) Released(button uint32) bool {
+	return this.State(button).Released
+}
+func (this *GamePad) DoubleTapped(button uint32) bool {
+	return this.State(button).DoubleTapped
+}
+func (this *GamePad) HeldFor(button uint32) float32 {
+	return this.State(button).HeldFor
+}
+
+// SetTriggerThreshold sets how far the triggers must be pulled to hold
+// ButtonLT and ButtonRT.
+func (this *GamePad) SetTriggerThreshold(threshold Threshold) {
+	this.settings.triggerThreshold = threshold
+}
+
+// SetStickThreshold sets how far the sticks must be pushed along an axis to
+// hold the stick direction buttons.
+func (this *GamePad) SetStickThreshold(threshold Threshold) {
+	this.settings.stickThreshold = threshold
+}
+
+// SetDoubleTapTime sets the longest gap in seconds between the presses of a
+// double tap.
+func (this *GamePad) SetDoubleTapTime(seconds float32) {
+	this.settings.doubleTapTime = seconds
+}
+
+// virtualButtons works out which virtual buttons are held, given which were
+// held last tick.
+func (this *GamePad) virtualButtons(last uint32) uint32 {
+	var held uint32
+	values := [...]float32{
+		this.LeftTrigger, this.RightTrigger,
+		this.LeftStick.Y(), -this.LeftStick.Y(), -this.LeftStick.X(), this.LeftStick.X(),
+		this.RightStick.Y(), -this.RightStick.Y(), -this.RightStick.X(), this.RightStick.X(),
+	}
+	for i, value := range values {
+		button := uint32(ButtonLT) << uint(i)
+		threshold := this.settings.stickThreshold
+		if i < 2 {
+			threshold = this.settings.triggerThreshold
+		}
+		if threshold.apply(value, last&button != 0) {
+			held |= button
+		}
+	}
+	return held
+}
+
+// updateStates works out the state of every button from the buttons held,
+// the states last tick and the seconds since it.
+func (this *GamePad) updateStates(buttons uint16, last *GamePad, delta float32) {
+	var lastHeld uint32
+	for i := range last.States {
+		if last.States[i].Down {
+			lastHeld |= 1 << uint(i)
+		}
+	}
+	held := uint32(buttons)
+	if this.Active {
+		held |= this.virtualButtons(lastHeld)
+	}
+	window := this.settings.doubleTapTime
+	for i := range this.States {
+		was := last.States[i]
+		state := ButtonState{Down: held&(1<<uint(i)) != 0, sinceTap: was.sinceTap + delta}
+		state.Pressed = state.Down && !was.Down
+		state.Released = !state.Down && was.Down
+		if state.Pressed {
+			state.DoubleTapped = was.tapPending && state.sinceTap <= window
+			state.tapPending = !state.DoubleTapped
+			state.sinceTap = 0
+		} else {
+			state.tapPending = was.tapPending && state.sinceTap <= window
+		}
+		if state.Down && !state.Pressed || state.Released {
+			state.HeldFor = was.HeldFor + delta
+		}
+		this.States[i] = state
+	}
+}
@@ -0,0 +1,137 @@
+package input
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+// A DeadzoneShape decides how a stick's two axes are measured against its
+// deadzone.
+type DeadzoneShape int
+
+const (
+	// DeadzoneScaledRadial ignores the stick until its distance from center
+	// passes Inner, then rescales the distance so output starts from 0.
+	DeadzoneScaledRadial DeadzoneShape = iota
+	// DeadzoneRadial ignores the stick until its distance from center passes
+	// Inner, then reports it unscaled, so output jumps from 0 to Inner.
+	DeadzoneRadial
+	// DeadzoneAxial applies the deadzone to each axis on its own, which snaps
+	// near-straight pushes onto the axis.
+	DeadzoneAxial
+)
+
+// A Deadzone maps a raw value from 0 to 1 onto a response from 0 to 1. Values
+// up to Inner read 0 and values from Outer read 1; between them the value is
+// rescaled to 0 to 1 and shaped by Curve if set, otherwise raised to
+// Exponent. Zero Outer and Exponent mean 1.
+type Deadzone struct {
+	Inner, Outer float32
+	Exponent     float32
+	Curve        func(float32) float32
+}
+
+// A StickDeadzone is a Deadzone applied to a stick in the given shape.
+type StickDeadzone struct {
+	Shape DeadzoneShape
+	Deadzone
+}
+
+// DefaultStickDeadzone is the stick deadzone pads start with.
+var DefaultStickDeadzone = StickDeadzone{Shape: DeadzoneScaledRadial, Deadzone: Deadzone{Inner: 0.1, Outer: 1, Exponent: 1}}
+
+// DefaultTriggerDeadzone is the trigger deadzone pads start with.
+var DefaultTriggerDeadzone = Deadzone{Inner: 0, Outer: 1, Exponent: 1}
+
+func (this Deadzone) outer() float32 {
+	if this.Outer <= this.Inner || this.Outer == 0 {
+		return 1
+	}
+	return this.Outer
+}
+
+// rescale maps v onto 0 to 1 between Inner and Outer.
+func (this Deadzone) rescale(v float32) float32 {
+	outer := this.outer()
+	if v <= this.Inner {
+		return 0
+	}
+	if v >= outer {
+		return 1
+	}
+	return (v - this.Inner) / (outer - this.Inner)
+}
+
+// curve shapes a rescaled value.
+func (this Deadzone) curve(v float32) float32 {
+	if this.Curve != nil {
+		return mgl32.Clamp(this.Curve(v), 0, 1)
+	}
+	if this.Exponent == 0 || this.Exponent == 1 {
+		return v
+	}
+	return float32(math.Pow(float64(v), float64(this.Exponent)))
+}
+
+// Apply maps a raw trigger or axis magnitude from 0 to 1 onto its response.
+func (this Deadzone) Apply(v float32) float32 {
+	return this.curve(this.rescale(v))
+}
+
+// Apply maps a raw stick position onto its response.
+func (this StickDeadzone) Apply(stick mgl32.Vec2) mgl32.Vec2 {
+	switch this.Shape {
+	case DeadzoneAxial:
+		for i := range stick {
+			v := this.Deadzone.Apply(float32(math.Abs(float64(stick[i]))))
+			if stick[i] < 0 {
+				v = -v
+			}
+			stick[i] = v
+		}
+		if stick.Len() > 1 {
+			stick = stick.Normalize()
+		}
+		return stick
+	case DeadzoneRadial:
+		dist := stick.Len()
+		if dist <= this.Inner {
+			return mgl32.Vec2{}
+		}
+		dist = this.curve(mgl32.Clamp(dist, 0, this.outer()) / this.outer())
+		return stick.Normalize().Mul(dist)
+	default:
+		dist := stick.Len()
+		if dist <= this.Inner {
+			return mgl32.Vec2{}
+		}
+		return stick.Normalize().Mul(this.Deadzone.Apply(dist))
+	}
+}
+
+// SetLeftStickDeadzone sets the deadzone of the stick reported as LeftStick.
+func (this *GamePad) SetLeftStickDeadzone(deadzone StickDeadzone) {
+	this.settings.sticks[0] = deadzone
+}
+
+// SetRightStickDeadzone sets the deadzone of the stick reported as RightStick.
+func (this *GamePad) SetRightStickDeadzone(deadzone StickDeadzone) {
+	this.settings.sticks[1] = deadzone
+}
+
+// SetLeftTriggerDeadzone sets the deadzone of the trigger reported as LeftTrigger.
+func (this *GamePad) SetLeftTriggerDeadzone(deadzone Deadzone) {
+	this.settings.triggers[0] = deadzone
+}
+
+// SetRightTriggerDeadzone sets the deadzone of the trigger reported as RightTrigger.
+func (this *GamePad) SetRightTriggerDeadzone(deadzone Deadzone) {
+	this.settings.triggers[1] = deadzone
+}
+
+func (this *GamePad) StickDeadzones() (left, right StickDeadzone) {
+	return this.settings.sticks[0], this.settings.sticks[1]
+}
+func (this *GamePad) TriggerDeadzones() (left, right Deadzone) {
+	return this.settings.triggers[0], this.settings.triggers[1]
+}
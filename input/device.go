@@ -0,0 +1,112 @@
+package input
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Button bits, in the XInput layout every Device reports buttons in. They
+// fit in a uint16; the virtual buttons of GamePad follow them.
+const (
+	ButtonUp     = 0x0001
+	ButtonDown   = 0x0002
+	ButtonLeft   = 0x0004
+	ButtonRight  = 0x0008
+	ButtonStart  = 0x0010
+	ButtonSelect = 0x0020
+	ButtonLS     = 0x0040
+	ButtonRS     = 0x0080
+	ButtonLB     = 0x0100
+	ButtonRB     = 0x0200
+	ButtonA      = 0x1000
+	ButtonB      = 0x2000
+	ButtonX      = 0x4000
+	ButtonY      = 0x8000
+)
+
+// Capabilities are what a controller can do beyond reporting its buttons.
+type Capabilities struct {
+	Rumble         bool
+	AnalogTriggers bool
+	Wireless       bool
+}
+
+// PadInfo identifies a controller. ID is stable for as long as the
+// controller is known to the device, and across reconnections where the
+// backend allows it, so Input can give a returning controller its old slot.
+type PadInfo struct {
+	ID, Name     string
+	Capabilities Capabilities
+}
+
+// A RawPad is one controller's state as read by a Device, before remaps and
+// deadzones. Triggers run from 0 to 1 and sticks from -1 to 1 with up positive.
+type RawPad struct {
+	Connected                 bool
+	Info                      PadInfo
+	Buttons                   uint16
+	LeftTrigger, RightTrigger float32
+	LeftStick, RightStick     mgl32.Vec2
+}
+
+// A Device reads the raw state of up to four controllers.
+type Device interface {
+	Poll() [4]RawPad
+}
+
+// A Rumbler is a Device that can drive the vibration motors of its
+// controllers, with intensities from 0 to 1. Rumble is dropped on devices
+// that are not Rumblers.
+type Rumbler interface {
+	Rumble(pad int, left, right float32)
+}
+
+// A VirtualDevice is a Device whose controllers are set from code, for tests,
+// scripted input and running without hardware. Each Poll first takes the next
+// state queued with Queue, if any, then reports Pads.
+type VirtualDevice struct {
+	Pads [4]RawPad
+	// Motors holds the last intensities each pad's motors were set to.
+	Motors [4][2]float32
+	queue  [][4]RawPad
+}
+
+func (this *VirtualDevice) Poll() [4]RawPad {
+	if len(this.queue) > 0 {
+		this.Pads = this.queue[0]
+		this.queue = this.queue[1:]
+	}
+	return this.Pads
+}
+
+func (this *VirtualDevice) Rumble(pad int, left, right float32) {
+	this.Motors[pad] = [2]float32{left, right}
+}
+
+// Queue schedules states to be reported by successive Polls.
+func (this *VirtualDevice) Queue(states ...[4]RawPad) {
+	this.queue = append(this.queue, states...)
+}
+
+// Press connects pad and holds down buttons.
+func (this *VirtualDevice) Press(pad int, buttons uint16) {
+	this.Pads[pad].Connected = true
+	this.Pads[pad].Buttons |= buttons
+}
+func (this *VirtualDevice) Release(pad int, buttons uint16) {
+	this.Pads[pad].Buttons &^= buttons
+}
+
+// SetSticks connects pad and moves its sticks.
+func (this *VirtualDevice) SetSticks(pad int, left, right mgl32.Vec2) {
+	this.Pads[pad].Connected = true
+	this.Pads[pad].LeftStick, this.Pads[pad].RightStick = left, right
+}
+
+// SetTriggers connects pad and pulls its triggers.
+func (this *VirtualDevice) SetTriggers(pad int, left, right float32) {
+	this.Pads[pad].Connected = true
+	this.Pads[pad].LeftTrigger, this.Pads[pad].RightTrigger = left, right
+}
+func (this *VirtualDevice) Disconnect(pad int) {
+	this.Pads[pad] = RawPad{}
+}
@@ -0,0 +1,67 @@
+//go:build !windows
+// +build !windows
+
+package input
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// XInput bits of the GLFW gamepad buttons, indexed by glfw.GamepadButton.
+var glfwButtons = [...]uint16{
+	glfw.ButtonA:           ButtonA,
+	glfw.ButtonB:           ButtonB,
+	glfw.ButtonX:           ButtonX,
+	glfw.ButtonY:           ButtonY,
+	glfw.ButtonLeftBumper:  ButtonLB,
+	glfw.ButtonRightBumper: ButtonRB,
+	glfw.ButtonBack:        ButtonSelect,
+	glfw.ButtonStart:       ButtonStart,
+	glfw.ButtonGuide:       0,
+	glfw.ButtonLeftThumb:   ButtonLS,
+	glfw.ButtonRightThumb:  ButtonRS,
+	glfw.ButtonDpadUp:      ButtonUp,
+	glfw.ButtonDpadRight:   ButtonRight,
+	glfw.ButtonDpadDown:    ButtonDown,
+	glfw.ButtonDpadLeft:    ButtonLeft,
+}
+
+// A glfwDevice reads the first four joysticks GLFW has a gamepad mapping
+// for. glfw.Init must have been called. GLFW has no force feedback, so it is
+// not a Rumbler. Controllers are identified by their SDL GUID, which is the
+// same for every controller of a model, so identical controllers may trade
+// slots when reconnected.
+type glfwDevice struct{}
+
+func platformDevice() Device {
+	return glfwDevice{}
+}
+func (this glfwDevice) Poll() [4]RawPad {
+	var pads [4]RawPad
+	i := 0
+	for joy := glfw.Joystick1; joy <= glfw.JoystickLast && i < 4; joy++ {
+		if !joy.IsGamepad() {
+			continue
+		}
+		state := joy.GetGamepadState()
+		if state == nil {
+			continue
+		}
+		pads[i].Connected = true
+		pads[i].Info = PadInfo{ID: joy.GetGUID(), Name: joy.GetGamepadName(),
+			Capabilities: Capabilities{AnalogTriggers: true}}
+		for button, bit := range glfwButtons {
+			if state.Buttons[button] == glfw.Press {
+				pads[i].Buttons |= bit
+			}
+		}
+		// GLFW triggers run from -1 to 1 and its sticks have down positive.
+		pads[i].LeftTrigger = (state.Axes[glfw.AxisLeftTrigger] + 1) / 2
+		pads[i].RightTrigger = (state.Axes[glfw.AxisRightTrigger] + 1) / 2
+		pads[i].LeftStick = mgl32.Vec2{state.Axes[glfw.AxisLeftX], -state.Axes[glfw.AxisLeftY]}
+		pads[i].RightStick = mgl32.Vec2{state.Axes[glfw.AxisRightX], -state.Axes[glfw.AxisRightY]}
+		i++
+	}
+	return pads
+}
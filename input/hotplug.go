@@ -0,0 +1,104 @@
+package input
+
+import (
+	"fmt"
+)
+
+type PadEventKind int
+
+const (
+	PadConnected PadEventKind = iota
+	PadDisconnected
+)
+
+// A PadEvent reports a controller connecting to or disconnecting from the
+// slot Pad of GamePads.
+type PadEvent struct {
+	Kind PadEventKind
+	Pad  int
+	Info PadInfo
+}
+
+func (this PadEvent) String() string {
+	if this.Kind == PadConnected {
+		return fmt.Sprintf("Controller %d connected: %s", this.Pad+1, this.Info.Name)
+	}
+	return fmt.Sprintf("Controller %d disconnected: %s", this.Pad+1, this.Info.Name)
+}
+
+// assignSlots moves the controllers the device reported into slots. A
+// controller goes back to the last slot it had, so it keeps its settings and
+// whatever the app tied to that slot; a new one takes the first slot no
+// controller has had, or failing that the first free one. Controllers
+// without an ID are identified by their place in the device's list.
+func (this *Input) assignSlots(states [4]RawPad) [4]RawPad {
+	var slotted [4]RawPad
+	var taken [4]bool
+	var waiting []int
+	for i := range states {
+		state := &states[i]
+		if !state.Connected {
+			continue
+		}
+		if state.Info.ID == "" {
+			state.Info.ID = fmt.Sprintf("device.%d", i)
+		}
+		slot := -1
+		for s := range this.slots {
+			if !taken[s] && this.slots[s] == state.Info.ID {
+				slot = s
+				break
+			}
+		}
+		if slot < 0 {
+			waiting = append(waiting, i)
+			continue
+		}
+		slotted[slot], taken[slot] = *state, true
+		this.devicePads[slot] = i
+	}
+	for _, i := range waiting {
+		state := states[i]
+		slot := -1
+		for s := range this.slots {
+			if !taken[s] && this.slots[s] == "" {
+				slot = s
+				break
+			}
+		}
+		for s := 0; slot < 0 && s < len(this.slots); s++ {
+			if !taken[s] {
+				slot = s
+			}
+		}
+		this.slots[slot] = state.Info.ID
+		slotted[slot], taken[slot] = state, true
+		this.devicePads[slot] = i
+	}
+	return slotted
+}
+
+// padEvents lists the controllers that connected or disconnected since the
+// last Get.
+func (this *Input) padEvents() []PadEvent {
+	var events []PadEvent
+	for i := range this.GamePads {
+		was, is := this.lastGamePads[i], this.GamePads[i]
+		if was.Active && (!is.Active || was.Info.ID != is.Info.ID) {
+			events = append(events, PadEvent{Kind: PadDisconnected, Pad: i, Info: was.Info})
+		}
+		if is.Active && (!was.Active || was.Info.ID != is.Info.ID) {
+			events = append(events, PadEvent{Kind: PadConnected, Pad: i, Info: is.Info})
+		}
+	}
+	return events
+}
+
+// Forget clears the slot pad so the next new controller can take it, along
+// with its settings.
+func (this *Input) Forget(pad int) {
+	this.slots[pad] = ""
+	if pad < len(this.GamePads) {
+		this.GamePads[pad].settings = defaultPadSettings()
+	}
+}
@@ -1,159 +1,103 @@
 package input
 
-/*
-#cgo LDFLAGS: -L./ -lXinput9_1_0
-#include <xinput.h>
-#include <WinError.h>
-#include <stdio.h>
-
-typedef struct{
-  XINPUT_STATE p1, p2, p3, p4;
-  int v1, v2, v3, v4;
-} inputState;
-
-static inputState states;
-
-inputState getXInput( void  ){
-  XINPUT_STATE state;
-
-  XINPUT_STATE* xs[] = { &states.p1, &states.p2, &states.p3, &states.p4 };
-  int* v[] = { &states.v1, &states.v2, &states.v3, &states.v4 };
-  for( int i = 0; i < 4; ++i ){
-    memset( xs[ i ], 0, sizeof( XINPUT_STATE ) );
-    if( XInputGetState( i, &state ) == ERROR_SUCCESS ){
-      *v[ i ] = 1;
-      memcpy( xs[ i ], &state, sizeof( XINPUT_STATE ) );
-    }else
-      *v[ i ] = 0;
-  }
-
-  return states;
-}
-*/
-import "C"
-
 import (
 	"bytes"
 	"fmt"
 	"github.com/go-gl/mathgl/mgl32"
+	"time"
 )
 
 type GamePad struct {
-	Active                                              bool
-	LeftTrigger, RightTrigger                           float32
-	LeftStick, RightStick, Dpad                         mgl32.Vec2
-	Start, Select, X, Y, A, B, LB, RB, LS, RS           bool
-	StartP, SelectP, XP, YP, AP, BP, LBP, RBP, LSP, RSP bool
-	UpP, DownP, LeftP, RightP                           bool
-	swaps                                               [][2]uint16
-	swapSticks                                          bool
-	swapTriggers                                        bool
+	Active                                    bool
+	LeftTrigger, RightTrigger                 float32
+	LeftStick, RightStick, Dpad               mgl32.Vec2
+	Start, Select, X, Y, A, B, LB, RB, LS, RS bool
+	// States holds the state of every button, real and virtual, by bit
+	// number. Query it with State, Pressed, Released, Held and so on.
+	States [numButtons]ButtonState
+	// Info describes the controller in the slot, or the last one that was.
+	Info     PadInfo
+	settings padSettings
 }
 
-func (this *GamePad) ResetSwaps() {
-	this.swaps = make([][2]uint16, 0)
-	this.swapSticks = false
-	this.swapTriggers = false
+// padSettings are the parts of a GamePad set from code, carried over from
+// one Get to the next.
+type padSettings struct {
+	remap    RemapTable
+	sticks   [2]StickDeadzone
+	triggers [2]Deadzone
+	rumbles  []rumble
+	motors   [2]float32
+	// The level set with SetRumble.
+	sustain [2]float32
+	// Thresholds of the virtual buttons, and the double tap window.
+	triggerThreshold Threshold
+	stickThreshold   Threshold
+	doubleTapTime    float32
 }
-func (this *GamePad) SwapSticks(v bool) {
-	this.swapSticks = v
-}
-func (this *GamePad) SwapTriggers(v bool) {
-	this.swapTriggers = v
-}
-func (this *GamePad) Swap(b1 *bool, b2 *bool) {
-	if this.swaps == nil {
-		this.swaps = make([][2]uint16, 0)
-	}
 
-	args := [2]*bool{b1, b2}
-	ans := [2]uint16{}
-	for i := 0; i < 2; i++ {
-		switch args[i] {
-		case &this.Start:
-			ans[i] = 0x0010
-		case &this.Select:
-			ans[i] = 0x0020
-		case &this.LS:
-			ans[i] = 0x0040
-		case &this.RS:
-			ans[i] = 0x0080
-		case &this.LB:
-			ans[i] = 0x0100
-		case &this.RB:
-			ans[i] = 0x0200
-		case &this.A:
-			ans[i] = 0x1000
-		case &this.B:
-			ans[i] = 0x2000
-		case &this.X:
-			ans[i] = 0x4000
-		case &this.Y:
-			ans[i] = 0x8000
-		}
+func defaultPadSettings() padSettings {
+	return padSettings{
+		sticks:           [2]StickDeadzone{DefaultStickDeadzone, DefaultStickDeadzone},
+		triggers:         [2]Deadzone{DefaultTriggerDeadzone, DefaultTriggerDeadzone},
+		triggerThreshold: DefaultThreshold,
+		stickThreshold:   DefaultThreshold,
+		doubleTapTime:    DefaultDoubleTapTime,
 	}
-	this.swaps = append(this.swaps, ans)
 }
-func (this *GamePad) SwapDpad(dir mgl32.Vec2, b2 *bool) {
-	if this.swaps == nil {
-		this.swaps = make([][2]uint16, 0)
-	}
 
-	ans := [2]uint16{}
-	if dir[0] > 0.9 {
-		ans[0] = 0x0008
-	} else if dir[0] < -0.9 {
-		ans[0] = 0x0004
-	} else {
-		if dir[1] > 0.9 {
-			ans[0] = 0x0001
-		} else {
-			ans[0] = 0x0002
-		}
-	}
-	switch b2 {
-	case &this.Start:
-		ans[1] = 0x0010
-	case &this.Select:
-		ans[1] = 0x0020
-	case &this.LS:
-		ans[1] = 0x0040
-	case &this.RS:
-		ans[1] = 0x0080
-	case &this.LB:
-		ans[1] = 0x0100
-	case &this.RB:
-		ans[1] = 0x0200
-	case &this.A:
-		ans[1] = 0x1000
-	case &this.B:
-		ans[1] = 0x2000
-	case &this.X:
-		ans[1] = 0x4000
-	case &this.Y:
-		ans[1] = 0x8000
-	}
+var buttonNames = map[uint32]string{
+	ButtonUp: "Up", ButtonDown: "Down", ButtonLeft: "Left", ButtonRight: "Right",
+	ButtonStart: "Start", ButtonSelect: "Select", ButtonLS: "LS", ButtonRS: "RS",
+	ButtonLB: "LB", ButtonRB: "RB", ButtonA: "A", ButtonB: "B", ButtonX: "X", ButtonY: "Y",
+}
 
-	this.swaps = append(this.swaps, ans)
+// RemapString describes the remaps set on the pad, one per line.
+func (this *GamePad) RemapString() string {
+	return this.settings.remap.String()
 }
 
 type Mouse struct {
 	Delta               mgl32.Vec2
 	Left, Middle, Right bool
 	Scroll              mgl32.Vec2
+	// Look is mouse movement as a stick, right and up positive, processed
+	// as Settings say, or DefaultMouseSettings if they are zero. In
+	// MouseJoystick mode Stick is the virtual stick's
+	// position before its deadzone, for drawing a reticle.
+	Look, Stick mgl32.Vec2
+	Settings    MouseSettings
+}
+
+func (this Mouse) String() string {
+	return fmt.Sprintf("Mouse delta: %6.1fx%6.1f scroll: %3.fx%3.f left: %5t middle: %5t right: %5t\n"+
+		"Mouse look: %5.2fx%5.2f stick: %5.2fx%5.2f\n",
+		this.Delta.X(), this.Delta.Y(), this.Scroll.X(), this.Scroll.Y(), this.Left, this.Middle, this.Right,
+		this.Look.X(), this.Look.Y(), this.Stick.X(), this.Stick.Y())
 }
 
 type Input struct {
 	GamePads     []GamePad
 	lastGamePads []GamePad
 	Mouse        Mouse
+	// PadEvents lists the controllers that connected or disconnected during
+	// the last Get.
+	PadEvents []PadEvent
+	// IDs of the controllers last seen in each slot, and where in the
+	// device's list each was.
+	slots      [4]string
+	devicePads [4]int
+	lastGet    time.Time
+	// Device supplies the raw controller state. If nil, Get uses the
+	// platform's backend.
+	Device Device
 }
 
 func (this Input) String() string {
 	var buf bytes.Buffer
 	for i := 0; i < len(this.GamePads); i++ {
 		if this.GamePads[i].Active {
-			buf.WriteString(fmt.Sprintf("\n\nController %d\n", i))
+			buf.WriteString(fmt.Sprintf("\n\nController %d: %s\n", i, this.GamePads[i].Info.Name))
 			buf.WriteString(fmt.Sprintf("Left trigger: %5.2f               Right trigger: %5.2f\n",
 				this.GamePads[i].LeftTrigger, this.GamePads[i].RightTrigger))
 			buf.WriteString(fmt.Sprintf("Left stick:   %5.2fx%5.2f|%5.2f|  Right stick:   %5.2fx%5.2f|%5.2f|\n",
@@ -165,6 +109,15 @@ func (this Input) String() string {
 			buf.WriteString(fmt.Sprintf("A: %5t B: %5t X: %5t Y: %5t LS:%5t RS: %5t\n",
 				this.GamePads[i].A, this.GamePads[i].B, this.GamePads[i].X, this.GamePads[i].Y,
 				this.GamePads[i].LS, this.GamePads[i].RS))
+			buf.WriteString("Virtual:")
+			for bit := uint32(ButtonLT); bit <= ButtonRStickRight; bit <<= 1 {
+				if this.GamePads[i].Held(bit) {
+					buf.WriteString(" " + virtualButtonNames[bit])
+				}
+			}
+			buf.WriteString("\n")
+			buf.WriteString(fmt.Sprintf("Rumble: %4.2f %4.2f\n", this.GamePads[i].settings.motors[0],
+				this.GamePads[i].settings.motors[1]))
 
 		}
 	}
@@ -174,187 +127,85 @@ func (this Input) String() string {
 
 // Get populates the inputs slices.
 func (this *Input) Get() {
-	var swapss [4][][2]uint16
-	var swapsst, swapstr [4]bool
-	if len(this.GamePads) == 4 {
-		swapss = [4][][2]uint16{
-			this.GamePads[0].swaps,
-			this.GamePads[1].swaps,
-			this.GamePads[2].swaps,
-			this.GamePads[3].swaps,
-		}
-		swapstr = [4]bool{
-			this.GamePads[0].swapTriggers,
-			this.GamePads[1].swapTriggers,
-			this.GamePads[2].swapTriggers,
-			this.GamePads[3].swapTriggers,
-		}
-		swapsst = [4]bool{
-			this.GamePads[0].swapSticks,
-			this.GamePads[1].swapSticks,
-			this.GamePads[2].swapSticks,
-			this.GamePads[3].swapSticks,
+	var settings [4]padSettings
+	for i := range settings {
+		if len(this.GamePads) == 4 {
+			settings[i] = this.GamePads[i].settings
+		} else {
+			settings[i] = defaultPadSettings()
 		}
 	}
 	this.lastGamePads = this.GamePads
 	if this.lastGamePads == nil {
-		this.lastGamePads = make([]GamePad,4)
+		this.lastGamePads = make([]GamePad, 4)
 	}
 	this.GamePads = nil
-	cstates := C.getXInput()
-	states := [4]C.XINPUT_STATE{cstates.p1, cstates.p2, cstates.p3, cstates.p4}
-	valids := [4]bool{int(cstates.v1) != 0, int(cstates.v2) != 0,
-		int(cstates.v3) != 0, int(cstates.v4) != 0}
+	if this.Device == nil {
+		this.Device = platformDevice()
+	}
+	states := this.assignSlots(this.Device.Poll())
+	now := time.Now()
+	var delta float32
+	if !this.lastGet.IsZero() {
+		delta = float32(now.Sub(this.lastGet).Seconds())
+	}
+	this.lastGet = now
 	for i := 0; i < 4; i++ {
-		if valids[i] {
-
-			// Get buttons and do swaps
-			buttons := uint16(states[i].Gamepad.wButtons)
-			for j := 0; j < len(swapss[i]); j++ {
-
-				b1 := swapss[i][j][0]
-				b2 := swapss[i][j][1]
-				t1 := b1&buttons != 0
-				t2 := b2&buttons != 0
-				if t1 {
-					buttons &^= b2
-					buttons ^= b2
-				} else {
-					buttons &^= b2
-				}
-				if t2 {
-					buttons &^= b1
-					buttons ^= b1
-				} else {
-					buttons &^= b1
-				}
-			}
+		var buttons uint16
+		if states[i].Connected {
 
+			raw := settings[i].remap.apply(states[i])
+			buttons = raw.Buttons
 			var dx, dy float32 = 0, 0
-			if buttons&0x0004 != 0 {
+			if buttons&ButtonLeft != 0 {
 				dx -= 1
 			}
-			if buttons&0x0008 != 0 {
+			if buttons&ButtonRight != 0 {
 				dx += 1
 			}
-			if buttons&0x0002 != 0 {
+			if buttons&ButtonDown != 0 {
 				dy -= 1
 			}
-			if buttons&0x0001 != 0 {
+			if buttons&ButtonUp != 0 {
 				dy += 1
 			}
-			trigs := [2]float32{
-				float32(states[i].Gamepad.bLeftTrigger) / 255.0,
-				float32(states[i].Gamepad.bRightTrigger) / 255.0,
-			}
-			sticks := [2]mgl32.Vec2{
-				mgl32.Vec2{
-					(float32(states[i].Gamepad.sThumbLX) + 0.5) / 32767.5,
-					(float32(states[i].Gamepad.sThumbLY) + 0.5) / 32767.5,
-				},
-				mgl32.Vec2{
-					(float32(states[i].Gamepad.sThumbRX) + 0.5) / 32767.5,
-					(float32(states[i].Gamepad.sThumbRY) + 0.5) / 32767.5,
-				},
-			}
-			if swapsst[i] {
-				temp := sticks[0]
-				sticks[0] = sticks[1]
-				sticks[1] = temp
-			}
-			if swapstr[i] {
-				temp := trigs[0]
-				trigs[0] = trigs[1]
-				trigs[1] = temp
-			}
-
-			for i := 0; i < 2; i++ {
-				if sticks[i].Len() > 1 {
-					sticks[i] = sticks[i].Normalize()
-				}
-				if sticks[i].Len() < 0.1 {
-					sticks[i][0] = 0
-					sticks[i][1] = 0
-				} else {
-					dist := sticks[i].Len()
-					dist -= 0.1
-					dist *= 10.0 / 9.0
-					sticks[i] = sticks[i].Normalize()
-					sticks[i][0] *= dist
-					sticks[i][1] *= dist
-				}
+			trigs := [2]float32{raw.LeftTrigger, raw.RightTrigger}
+			sticks := [2]mgl32.Vec2{raw.LeftStick, raw.RightStick}
+			for j := 0; j < 2; j++ {
+				sticks[j] = settings[i].sticks[j].Apply(sticks[j])
+				trigs[j] = settings[i].triggers[j].Apply(trigs[j])
 			}
 
 			this.GamePads = append(this.GamePads,
 				GamePad{
 					Active:       true,
+					Info:         states[i].Info,
 					LeftTrigger:  trigs[0],
 					RightTrigger: trigs[1],
 					LeftStick:    sticks[0],
 					RightStick:   sticks[1],
 					Dpad:         mgl32.Vec2{dx, dy},
-					Start:        buttons&0x0010 != 0,
-					Select:       buttons&0x0020 != 0,
-					LB:           buttons&0x0100 != 0,
-					RB:           buttons&0x0200 != 0,
-					LS:           buttons&0x0040 != 0,
-					RS:           buttons&0x0080 != 0,
-					A:            buttons&0x1000 != 0,
-					B:            buttons&0x2000 != 0,
-					X:            buttons&0x4000 != 0,
-					Y:            buttons&0x8000 != 0,
-					swaps:        swapss[i],
-					swapTriggers: swapstr[i],
-					swapSticks:   swapsst[i],
+					Start:        buttons&ButtonStart != 0,
+					Select:       buttons&ButtonSelect != 0,
+					LB:           buttons&ButtonLB != 0,
+					RB:           buttons&ButtonRB != 0,
+					LS:           buttons&ButtonLS != 0,
+					RS:           buttons&ButtonRS != 0,
+					A:            buttons&ButtonA != 0,
+					B:            buttons&ButtonB != 0,
+					X:            buttons&ButtonX != 0,
+					Y:            buttons&ButtonY != 0,
+					settings:     settings[i],
 				})
 		} else {
-			this.GamePads = append(this.GamePads, GamePad{
-				swaps:        swapss[i],
-				swapTriggers: swapstr[i],
-				swapSticks:   swapsst[i],
-			})
-		}
-		if !this.lastGamePads[i].A && this.GamePads[i].A {
-			this.GamePads[i].AP = true
-		}
-		if !this.lastGamePads[i].B && this.GamePads[i].B {
-			this.GamePads[i].BP = true
-		}
-		if !this.lastGamePads[i].X && this.GamePads[i].X {
-			this.GamePads[i].XP = true
-		}
-		if !this.lastGamePads[i].Y && this.GamePads[i].Y {
-			this.GamePads[i].YP = true
-		}
-		if !this.lastGamePads[i].LB && this.GamePads[i].LB {
-			this.GamePads[i].LBP = true
-		}
-		if !this.lastGamePads[i].RB && this.GamePads[i].RB {
-			this.GamePads[i].RBP = true
-		}
-		if !this.lastGamePads[i].LS && this.GamePads[i].LS {
-			this.GamePads[i].LSP = true
-		}
-		if !this.lastGamePads[i].RS && this.GamePads[i].RS {
-			this.GamePads[i].RSP = true
-		}
-		if !this.lastGamePads[i].Start && this.GamePads[i].Start {
-			this.GamePads[i].StartP = true
-		}
-		if !this.lastGamePads[i].Select && this.GamePads[i].Select {
-			this.GamePads[i].SelectP = true
-		}
-		if this.lastGamePads[i].Dpad[0] != 1 && this.GamePads[i].Dpad[0] == 1 {
-			this.GamePads[i].RightP = true
-		}
-		if this.lastGamePads[i].Dpad[0] != -1 && this.GamePads[i].Dpad[0] == -1 {
-			this.GamePads[i].LeftP = true
-		}
-		if this.lastGamePads[i].Dpad[1] != 1 && this.GamePads[i].Dpad[1] == 1 {
-			this.GamePads[i].UpP = true
-		}
-		if this.lastGamePads[i].Dpad[1] != -1 && this.GamePads[i].Dpad[1] == -1 {
-			this.GamePads[i].DownP = true
+			info := PadInfo{ID: this.slots[i]}
+			if i < len(this.lastGamePads) && this.lastGamePads[i].Info.ID == this.slots[i] {
+				info = this.lastGamePads[i].Info
+			}
+			this.GamePads = append(this.GamePads, GamePad{Info: info, settings: settings[i]})
 		}
+		this.GamePads[i].updateStates(buttons, &this.lastGamePads[i], delta)
 	}
+	this.PadEvents = this.padEvents()
+	this.rumble()
 }
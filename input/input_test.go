@@ -0,0 +1,172 @@
+package input
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"testing"
+)
+
+// A frame is pad 0's raw state for one Get and what the pad should read
+// after it. The button masks must match exactly, virtual buttons included.
+type frame struct {
+	raw                       RawPad
+	held, pressed, released   uint32
+	leftStick, rightStick     mgl32.Vec2
+	leftTrigger, rightTrigger float32
+}
+
+func pad(buttons uint16) RawPad {
+	return RawPad{Connected: true, Buttons: buttons}
+}
+func stick(x, y float32) RawPad {
+	return RawPad{Connected: true, LeftStick: mgl32.Vec2{x, y}}
+}
+func trigger(v float32) RawPad {
+	return RawPad{Connected: true, LeftTrigger: v}
+}
+
+// buttonMask collects the buttons for which query holds.
+func buttonMask(query func(uint32) bool) uint32 {
+	var mask uint32
+	for i := uint(0); i < numButtons; i++ {
+		if query(1 << i) {
+			mask |= 1 << i
+		}
+	}
+	return mask
+}
+
+func TestGet(t *testing.T) {
+	cases := []struct {
+		name  string
+		remap string
+		setup func(*GamePad)
+		// A connected, idle pad is polled once before these.
+		frames []frame
+	}{
+		{name: "press, hold and release", frames: []frame{
+			{raw: pad(ButtonA), held: ButtonA, pressed: ButtonA},
+			{raw: pad(ButtonA), held: ButtonA},
+			{raw: pad(0), released: ButtonA},
+		}},
+		{name: "two buttons", frames: []frame{
+			{raw: pad(ButtonA | ButtonLB), held: ButtonA | ButtonLB, pressed: ButtonA | ButtonLB},
+			{raw: pad(ButtonLB), held: ButtonLB, released: ButtonA},
+		}},
+		{name: "remap swaps A and B", remap: "pad:A = pad:B\npad:B = pad:A", frames: []frame{
+			{raw: pad(ButtonB), held: ButtonA, pressed: ButtonA},
+			{raw: pad(ButtonA | ButtonB), held: ButtonA | ButtonB, pressed: ButtonB},
+			{raw: pad(0), released: ButtonA | ButtonB},
+		}},
+		{name: "remap dpad to a button", remap: "pad:Up = pad:A\npad:A = pad:Up", frames: []frame{
+			{raw: pad(ButtonA), held: ButtonUp, pressed: ButtonUp},
+			{raw: pad(ButtonUp), held: ButtonA, pressed: ButtonA, released: ButtonUp},
+		}},
+		{name: "remap inverts an axis", remap: "axis:lefty = axis:lefty*-1", frames: []frame{
+			{raw: stick(0, 1), held: ButtonLStickDown, pressed: ButtonLStickDown, leftStick: mgl32.Vec2{0, -1}},
+		}},
+		{name: "default stick deadzone", frames: []frame{
+			{raw: stick(0.05, 0.05)},
+			{raw: stick(0.46, 0), leftStick: mgl32.Vec2{0.4, 0}},
+			{raw: stick(0.7, 0), held: ButtonLStickRight, pressed: ButtonLStickRight,
+				leftStick: mgl32.Vec2{0.6 / 0.9, 0}},
+			{raw: stick(0.4, 0), released: ButtonLStickRight, leftStick: mgl32.Vec2{0.3 / 0.9, 0}},
+		}},
+		{name: "scaled radial deadzone keeps direction", frames: []frame{
+			{raw: stick(0.6, 0.8), held: ButtonLStickUp | ButtonLStickRight,
+				pressed: ButtonLStickUp | ButtonLStickRight, leftStick: mgl32.Vec2{0.6, 0.8}},
+			{raw: stick(-0.3, 0.4), released: ButtonLStickUp | ButtonLStickRight,
+				leftStick: mgl32.Vec2{-0.3, 0.4}.Mul(0.4 / 0.9 / 0.5)},
+		}},
+		{name: "axial stick deadzone", setup: func(gp *GamePad) {
+			gp.SetLeftStickDeadzone(StickDeadzone{Shape: DeadzoneAxial, Deadzone: Deadzone{Inner: 0.2, Outer: 1}})
+		}, frames: []frame{
+			{raw: stick(0.1, 0.7), held: ButtonLStickUp, pressed: ButtonLStickUp, leftStick: mgl32.Vec2{0, 0.625}},
+		}},
+		{name: "trigger deadzone", setup: func(gp *GamePad) {
+			gp.SetLeftTriggerDeadzone(Deadzone{Inner: 0.2, Outer: 0.8})
+		}, frames: []frame{
+			{raw: trigger(0.1)},
+			{raw: trigger(0.35), leftTrigger: 0.25},
+			{raw: trigger(0.9), held: ButtonLT, pressed: ButtonLT, leftTrigger: 1},
+			{raw: trigger(0), released: ButtonLT},
+		}},
+		{name: "disconnecting releases held buttons", frames: []frame{
+			{raw: pad(ButtonStart), held: ButtonStart, pressed: ButtonStart},
+			{raw: RawPad{}, released: ButtonStart},
+		}},
+	}
+	for _, c := range cases {
+		device := &VirtualDevice{}
+		in := Input{Device: device}
+		device.Pads[0] = pad(0)
+		in.Get()
+		gp := &in.GamePads[0]
+		if c.remap != "" {
+			table, err := ParseRemapTable(c.remap)
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if err := gp.SetRemap(table); err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+		}
+		if c.setup != nil {
+			c.setup(gp)
+		}
+		for i, want := range c.frames {
+			device.Pads[0] = want.raw
+			in.Get()
+			gp := &in.GamePads[0]
+			if gp.Active != want.raw.Connected {
+				t.Errorf("%s, frame %d: active %t, want %t", c.name, i, gp.Active, want.raw.Connected)
+			}
+			for _, check := range []struct {
+				what  string
+				query func(uint32) bool
+				want  uint32
+			}{{"held", gp.Held, want.held}, {"pressed", gp.Pressed, want.pressed}, {"released", gp.Released, want.released}} {
+				if got := buttonMask(check.query); got != check.want {
+					t.Errorf("%s, frame %d: %s %#x, want %#x", c.name, i, check.what, got, check.want)
+				}
+			}
+			if !gp.LeftStick.ApproxEqualThreshold(want.leftStick, 1e-5) {
+				t.Errorf("%s, frame %d: left stick %v, want %v", c.name, i, gp.LeftStick, want.leftStick)
+			}
+			if !gp.RightStick.ApproxEqualThreshold(want.rightStick, 1e-5) {
+				t.Errorf("%s, frame %d: right stick %v, want %v", c.name, i, gp.RightStick, want.rightStick)
+			}
+			if !mgl32.FloatEqualThreshold(gp.LeftTrigger, want.leftTrigger, 1e-5) ||
+				!mgl32.FloatEqualThreshold(gp.RightTrigger, want.rightTrigger, 1e-5) {
+				t.Errorf("%s, frame %d: triggers %v %v, want %v %v", c.name, i,
+					gp.LeftTrigger, gp.RightTrigger, want.leftTrigger, want.rightTrigger)
+			}
+		}
+	}
+}
+
+func TestSetRumble(t *testing.T) {
+	device := &VirtualDevice{}
+	in := Input{Device: device}
+	device.Pads[0] = pad(0)
+	in.Get()
+	for i := 0; i < 100; i++ {
+		in.GamePads[0].SetRumble(0.5, float32(i)/100)
+		in.Get()
+	}
+	if n := len(in.GamePads[0].settings.rumbles); n != 0 {
+		t.Errorf("%d effects running, want 0", n)
+	}
+	if device.Motors[0] != [2]float32{0.5, 0.99} {
+		t.Errorf("motors %v, want [0.5 0.99]", device.Motors[0])
+	}
+	in.GamePads[0].Rumble(RumbleEffect{Left: 1, Duration: 10})
+	in.Get()
+	if device.Motors[0] != [2]float32{1, 0.99} {
+		t.Errorf("motors with an effect %v, want [1 0.99]", device.Motors[0])
+	}
+	in.GamePads[0].StopRumble()
+	in.Get()
+	if device.Motors[0] != [2]float32{} {
+		t.Errorf("motors after StopRumble %v, want still", device.Motors[0])
+	}
+}
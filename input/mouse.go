@@ -0,0 +1,81 @@
+package input
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+	"math"
+)
+
+type MouseMode int
+
+const (
+	// MouseRelative makes Look follow mouse movement.
+	MouseRelative MouseMode = iota
+	// MouseJoystick moves a virtual stick with the mouse, which stays where
+	// it is left, and makes Look its position after the deadzone.
+	MouseJoystick
+)
+
+// MouseSettings decide how mouse movement becomes Mouse.Look.
+type MouseSettings struct {
+	Mode MouseMode
+	// Sensitivity multiplies mouse movement, in pixels, in either mode.
+	Sensitivity float32
+	// Radius is how far in pixels, after Sensitivity, the virtual stick
+	// travels from center to full deflection.
+	Radius   float32
+	Deadzone StickDeadzone
+	// Smoothing is the time constant in seconds Look eases towards its
+	// target with. Zero disables smoothing.
+	Smoothing        float32
+	InvertX, InvertY bool
+}
+
+var DefaultMouseSettings = MouseSettings{
+	Mode:        MouseRelative,
+	Sensitivity: 1,
+	Radius:      200,
+	Deadzone:    StickDeadzone{Shape: DeadzoneScaledRadial, Deadzone: Deadzone{Inner: 0.05, Outer: 1, Exponent: 1}},
+}
+
+// Update works out Look and Stick from Delta, delta seconds after the last
+// Update. The engine calls it every tick.
+func (this *Mouse) Update(delta float32) {
+	settings := this.Settings
+	if settings.Sensitivity == 0 {
+		settings = DefaultMouseSettings
+	}
+	// Delta has left and up positive; Look has right and up, like a stick.
+	move := mgl32.Vec2{-this.Delta.X(), this.Delta.Y()}.Mul(settings.Sensitivity)
+	if settings.InvertX {
+		move[0] = -move[0]
+	}
+	if settings.InvertY {
+		move[1] = -move[1]
+	}
+	var target mgl32.Vec2
+	switch settings.Mode {
+	case MouseJoystick:
+		if settings.Radius > 0 {
+			this.Stick = this.Stick.Add(move.Mul(1 / settings.Radius))
+		}
+		if this.Stick.Len() > 1 {
+			this.Stick = this.Stick.Normalize()
+		}
+		target = settings.Deadzone.Apply(this.Stick)
+	default:
+		this.Stick = mgl32.Vec2{}
+		target = move
+	}
+	if settings.Smoothing <= 0 {
+		this.Look = target
+		return
+	}
+	blend := 1 - float32(math.Exp(float64(-delta/settings.Smoothing)))
+	this.Look = this.Look.Add(target.Sub(this.Look).Mul(blend))
+}
+
+// Recenter puts the virtual stick back in the middle and stops Look.
+func (this *Mouse) Recenter() {
+	this.Stick = mgl32.Vec2{}
+	this.Look = mgl32.Vec2{}
+}
@@ -0,0 +1,212 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A Remap makes the pad input To read the raw input From instead, scaled by
+// From.Scale. Both are pad buttons or pad axes, written as in bindings:
+// pad:A, axis:lefty*-1. A button reads 1 when held, and a button target is
+// held once its value reaches one half; a dpad axis target holds the dpad
+// direction its value points in.
+type Remap struct {
+	To, From Source
+}
+
+// A RemapTable lists remaps applied together: each reads the raw state, so
+// remaps never feed into one another, and inputs not remapped read
+// themselves. Swapping two inputs takes two entries.
+type RemapTable []Remap
+
+// ParseRemap parses a remap written as to = from, e.g. pad:A = pad:B.
+func ParseRemap(text string) (Remap, error) {
+	parts := strings.Split(text, "=")
+	if len(parts) != 2 {
+		return Remap{}, fmt.Errorf("remap %q is not to = from", text)
+	}
+	to, err := ParseSource(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return Remap{}, err
+	}
+	from, err := ParseSource(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Remap{}, err
+	}
+	return Remap{To: to, From: from}, nil
+}
+
+// ParseRemapTable parses one remap per line, skipping blank lines.
+func ParseRemapTable(text string) (RemapTable, error) {
+	var table RemapTable
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		remap, err := ParseRemap(line)
+		if err != nil {
+			return nil, err
+		}
+		table = append(table, remap)
+	}
+	return table, table.Validate()
+}
+
+func (this Remap) String() string {
+	return this.To.String() + " = " + this.From.String()
+}
+func (this RemapTable) String() string {
+	var buf bytes.Buffer
+	for _, remap := range this {
+		buf.WriteString(remap.String() + "\n")
+	}
+	return buf.String()
+}
+
+func validPadSource(source Source, what string) error {
+	switch source.Kind {
+	case SourcePadButton:
+		if _, ok := buttonNames[uint32(source.Code)]; !ok {
+			return fmt.Errorf("%s %s is not a real pad button", what, source)
+		}
+	case SourcePadAxis:
+		if source.Code < PadLeftX || source.Code > PadDpadY {
+			return fmt.Errorf("%s %s is not a pad axis", what, source)
+		}
+	default:
+		return fmt.Errorf("%s %s is not a pad button or axis", what, source)
+	}
+	return nil
+}
+
+// Validate checks that every entry maps a real pad button or axis to another
+// and that no input is the target of two entries, counting a dpad axis as its
+// two direction buttons. Targets cannot be scaled.
+func (this RemapTable) Validate() error {
+	var buttons uint32
+	axes := make(map[int]bool)
+	for _, remap := range this {
+		if err := validPadSource(remap.From, "source"); err != nil {
+			return err
+		}
+		if err := validPadSource(remap.To, "target"); err != nil {
+			return err
+		}
+		if remap.To.Scale != 1 {
+			return fmt.Errorf("target %s of %s is scaled", remap.To, remap)
+		}
+		var bits uint32
+		switch {
+		case remap.To.Kind == SourcePadButton:
+			bits = uint32(remap.To.Code)
+		case remap.To.Code == PadDpadX:
+			bits = ButtonLeft | ButtonRight
+		case remap.To.Code == PadDpadY:
+			bits = ButtonUp | ButtonDown
+		default:
+			if axes[remap.To.Code] {
+				return fmt.Errorf("%s is remapped twice", remap.To)
+			}
+			axes[remap.To.Code] = true
+		}
+		if buttons&bits != 0 {
+			return fmt.Errorf("%s is remapped twice", remap.To)
+		}
+		buttons |= bits
+	}
+	return nil
+}
+
+// SetRemap validates table and, if it is valid, makes it the pad's remap
+// table in place of the last one.
+func (this *GamePad) SetRemap(table RemapTable) error {
+	if err := table.Validate(); err != nil {
+		return err
+	}
+	this.settings.remap = append(RemapTable(nil), table...)
+	return nil
+}
+func (this *GamePad) Remap() RemapTable {
+	return this.settings.remap
+}
+
+// read is the value of a pad button or axis of raw.
+func read(raw *RawPad, source Source) float32 {
+	if source.Kind == SourcePadButton {
+		return boolValue(raw.Buttons&uint16(source.Code) != 0) * source.Scale
+	}
+	var value float32
+	switch source.Code {
+	case PadLeftX:
+		value = raw.LeftStick[0]
+	case PadLeftY:
+		value = raw.LeftStick[1]
+	case PadRightX:
+		value = raw.RightStick[0]
+	case PadRightY:
+		value = raw.RightStick[1]
+	case PadLeftTrigger:
+		value = raw.LeftTrigger
+	case PadRightTrigger:
+		value = raw.RightTrigger
+	case PadDpadX:
+		value = boolValue(raw.Buttons&ButtonRight != 0) - boolValue(raw.Buttons&ButtonLeft != 0)
+	case PadDpadY:
+		value = boolValue(raw.Buttons&ButtonUp != 0) - boolValue(raw.Buttons&ButtonDown != 0)
+	}
+	return value * source.Scale
+}
+
+// write sets a pad button or axis of pad to value.
+func write(pad *RawPad, target Source, value float32) {
+	setButton := func(bit uint16, held bool) {
+		if held {
+			pad.Buttons |= bit
+		} else {
+			pad.Buttons &^= bit
+		}
+	}
+	if target.Kind == SourcePadButton {
+		setButton(uint16(target.Code), value >= 0.5)
+		return
+	}
+	switch target.Code {
+	case PadLeftX:
+		pad.LeftStick[0] = clampAxis(value)
+	case PadLeftY:
+		pad.LeftStick[1] = clampAxis(value)
+	case PadRightX:
+		pad.RightStick[0] = clampAxis(value)
+	case PadRightY:
+		pad.RightStick[1] = clampAxis(value)
+	case PadLeftTrigger:
+		pad.LeftTrigger = clamp01(value)
+	case PadRightTrigger:
+		pad.RightTrigger = clamp01(value)
+	case PadDpadX:
+		setButton(ButtonRight, value >= 0.5)
+		setButton(ButtonLeft, value <= -0.5)
+	case PadDpadY:
+		setButton(ButtonUp, value >= 0.5)
+		setButton(ButtonDown, value <= -0.5)
+	}
+}
+func clampAxis(v float32) float32 {
+	if v < -1 {
+		return -1
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// apply remaps raw.
+func (this RemapTable) apply(raw RawPad) RawPad {
+	pad := raw
+	for _, remap := range this {
+		write(&pad, remap.To, read(&raw, remap.From))
+	}
+	return pad
+}
@@ -0,0 +1,127 @@
+package input
+
+import (
+	"time"
+)
+
+// A RumbleEffect drives a pad's vibration motors, Left the low-frequency one
+// and Right the high-frequency one, for Duration seconds. Intensity ramps up
+// from 0 over the first Attack seconds and back down over the last Release
+// seconds. Where effects overlap each motor follows the strongest.
+type RumbleEffect struct {
+	Left, Right     float32
+	Duration        float32
+	Attack, Release float32
+}
+
+type rumble struct {
+	effect RumbleEffect
+	start  time.Time
+}
+
+// envelope is the fraction of full intensity the effect has t seconds after
+// it started, and whether it has finished.
+func (this RumbleEffect) envelope(t float32) (float32, bool) {
+	if t >= this.Duration {
+		return 0, true
+	}
+	level := float32(1)
+	if this.Attack > 0 && t < this.Attack {
+		level = t / this.Attack
+	}
+	if left := this.Duration - t; this.Release > 0 && left < this.Release && left/this.Release < level {
+		level = left / this.Release
+	}
+	return level, false
+}
+
+// Rumble starts effect on the pad, for one-off feedback. It has no effect on
+// backends without force feedback.
+func (this *GamePad) Rumble(effect RumbleEffect) {
+	this.settings.rumbles = append(this.settings.rumbles, rumble{effect: effect, start: time.Now()})
+}
+
+// SetRumble holds the pad's motors at left and right until it is called
+// again, for continuous feedback that changes every tick. Effects started
+// with Rumble still run on top of it, and SetRumble(0, 0) ends it.
+func (this *GamePad) SetRumble(left, right float32) {
+	this.settings.sustain = [2]float32{clamp01(left), clamp01(right)}
+}
+
+// StopRumble cancels every effect on the pad and the level set with
+// SetRumble.
+func (this *GamePad) StopRumble() {
+	this.settings.rumbles = nil
+	this.settings.sustain = [2]float32{}
+}
+
+// Motors is the intensity the pad's motors were last set to.
+func (this *GamePad) Motors() (left, right float32) {
+	return this.settings.motors[0], this.settings.motors[1]
+}
+
+// updateRumble drops finished effects and works out the motor intensities
+// the rest and the sustained level add up to.
+func (this *padSettings) updateRumble(now time.Time) [2]float32 {
+	motors := this.sustain
+	running := this.rumbles[:0]
+	for _, r := range this.rumbles {
+		level, done := r.effect.envelope(float32(now.Sub(r.start).Seconds()))
+		if done {
+			continue
+		}
+		running = append(running, r)
+		if left := clamp01(r.effect.Left * level); left > motors[0] {
+			motors[0] = left
+		}
+		if right := clamp01(r.effect.Right * level); right > motors[1] {
+			motors[1] = right
+		}
+	}
+	this.rumbles = running
+	return motors
+}
+
+func clamp01(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// rumble sets the motors of every pad, telling the device only about
+// changes.
+func (this *Input) rumble() {
+	rumbler, ok := this.Device.(Rumbler)
+	now := time.Now()
+	for i := range this.GamePads {
+		settings := &this.GamePads[i].settings
+		motors := [2]float32{}
+		if this.GamePads[i].Active {
+			motors = settings.updateRumble(now)
+		} else {
+			settings.rumbles = nil
+			settings.sustain = [2]float32{}
+		}
+		if motors != settings.motors && ok && this.GamePads[i].Active {
+			rumbler.Rumble(this.devicePads[i], motors[0], motors[1])
+		}
+		settings.motors = motors
+	}
+}
+
+// StopRumble cancels every effect and stills every motor, e.g. before
+// exiting, since XInput pads keep vibrating after the process ends.
+func (this *Input) StopRumble() {
+	rumbler, ok := this.Device.(Rumbler)
+	for i := range this.GamePads {
+		this.GamePads[i].StopRumble()
+		this.GamePads[i].settings.motors = [2]float32{}
+		if ok && this.GamePads[i].Active {
+			rumbler.Rumble(this.devicePads[i], 0, 0)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package input
+
+// A Snapshot is the state of an Input for one tick: everything but the
+// settings made on its pads from code.
+type Snapshot struct {
+	GamePads  []GamePad
+	PadEvents []PadEvent
+	Mouse     Mouse
+}
+
+func (this *Input) Snapshot() Snapshot {
+	pads := make([]GamePad, len(this.GamePads))
+	for i := range pads {
+		pads[i] = this.GamePads[i]
+		pads[i].settings = padSettings{}
+	}
+	return Snapshot{GamePads: pads, PadEvents: this.PadEvents, Mouse: this.Mouse}
+}
+
+// Restore sets the state to snapshot in place of reading the devices,
+// keeping the pads' settings.
+func (this *Input) Restore(snapshot Snapshot) {
+	this.lastGamePads = this.GamePads
+	pads := make([]GamePad, len(snapshot.GamePads))
+	for i := range pads {
+		pads[i] = snapshot.GamePads[i]
+		if i < len(this.GamePads) {
+			pads[i].settings = this.GamePads[i].settings
+		} else {
+			pads[i].settings = defaultPadSettings()
+		}
+	}
+	this.GamePads = pads
+	this.PadEvents = snapshot.PadEvents
+	this.Mouse = snapshot.Mouse
+	if this.Device == nil {
+		this.Device = platformDevice()
+	}
+	this.rumble()
+}
@@ -0,0 +1,114 @@
+//go:build windows
+// +build windows
+
+package input
+
+/*
+#cgo LDFLAGS: -L./ -lXinput9_1_0
+#include <xinput.h>
+#include <WinError.h>
+#include <stdio.h>
+
+typedef struct{
+  XINPUT_STATE p1, p2, p3, p4;
+  int v1, v2, v3, v4;
+} inputState;
+
+static inputState states;
+
+inputState getXInput( void  ){
+  XINPUT_STATE state;
+
+  XINPUT_STATE* xs[] = { &states.p1, &states.p2, &states.p3, &states.p4 };
+  int* v[] = { &states.v1, &states.v2, &states.v3, &states.v4 };
+  for( int i = 0; i < 4; ++i ){
+    memset( xs[ i ], 0, sizeof( XINPUT_STATE ) );
+    if( XInputGetState( i, &state ) == ERROR_SUCCESS ){
+      *v[ i ] = 1;
+      memcpy( xs[ i ], &state, sizeof( XINPUT_STATE ) );
+    }else
+      *v[ i ] = 0;
+  }
+
+  return states;
+}
+
+int getXInputCaps( int i, int* rumble, int* wireless ){
+  XINPUT_CAPABILITIES caps;
+  if( XInputGetCapabilities( i, XINPUT_FLAG_GAMEPAD, &caps ) != ERROR_SUCCESS )
+    return 0;
+  *rumble = caps.Vibration.wLeftMotorSpeed != 0 || caps.Vibration.wRightMotorSpeed != 0;
+  *wireless = ( caps.Flags & XINPUT_CAPS_WIRELESS ) != 0;
+  return 1;
+}
+
+void setXInput( int i, unsigned short left, unsigned short right ){
+  XINPUT_VIBRATION vibration;
+  vibration.wLeftMotorSpeed = left;
+  vibration.wRightMotorSpeed = right;
+  XInputSetState( i, &vibration );
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// An xinputDevice reads the four XInput controllers and drives their motors.
+// XInput keeps a controller on the same user index while it is connected, so
+// the index serves as its ID.
+type xinputDevice struct {
+	// Info of each connected controller, read when it connects.
+	infos [4]*PadInfo
+}
+
+func platformDevice() Device {
+	return &xinputDevice{}
+}
+func (this *xinputDevice) info(i int) PadInfo {
+	if this.infos[i] == nil {
+		info := &PadInfo{ID: fmt.Sprintf("xinput.%d", i), Name: fmt.Sprintf("XInput controller %d", i+1),
+			Capabilities: Capabilities{AnalogTriggers: true}}
+		var rumble, wireless C.int
+		if C.getXInputCaps(C.int(i), &rumble, &wireless) != 0 {
+			info.Capabilities.Rumble = rumble != 0
+			info.Capabilities.Wireless = wireless != 0
+		}
+		this.infos[i] = info
+	}
+	return *this.infos[i]
+}
+func (this *xinputDevice) Poll() [4]RawPad {
+	var pads [4]RawPad
+	cstates := C.getXInput()
+	states := [4]C.XINPUT_STATE{cstates.p1, cstates.p2, cstates.p3, cstates.p4}
+	valids := [4]bool{int(cstates.v1) != 0, int(cstates.v2) != 0,
+		int(cstates.v3) != 0, int(cstates.v4) != 0}
+	for i := 0; i < 4; i++ {
+		if !valids[i] {
+			this.infos[i] = nil
+			continue
+		}
+		pads[i] = RawPad{
+			Connected:    true,
+			Info:         this.info(i),
+			Buttons:      uint16(states[i].Gamepad.wButtons),
+			LeftTrigger:  float32(states[i].Gamepad.bLeftTrigger) / 255.0,
+			RightTrigger: float32(states[i].Gamepad.bRightTrigger) / 255.0,
+			LeftStick: mgl32.Vec2{
+				(float32(states[i].Gamepad.sThumbLX) + 0.5) / 32767.5,
+				(float32(states[i].Gamepad.sThumbLY) + 0.5) / 32767.5,
+			},
+			RightStick: mgl32.Vec2{
+				(float32(states[i].Gamepad.sThumbRX) + 0.5) / 32767.5,
+				(float32(states[i].Gamepad.sThumbRY) + 0.5) / 32767.5,
+			},
+		}
+	}
+	return pads
+}
+func (this *xinputDevice) Rumble(pad int, left, right float32) {
+	C.setXInput(C.int(pad), C.ushort(left*65535), C.ushort(right*65535))
+}
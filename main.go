@@ -3,17 +3,19 @@ package main
 import (
 	"./engine"
 	"./input"
+	"flag"
 	"fmt"
 	"github.com/go-gl/gl/v4.3-core/gl"
-	"github.com/go-gl/glfw/v3.2/glfw"
+	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 	"math"
 	"math/rand"
-	"strconv"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
-func vertexShader(numSlices int) string {
+func vertexShader() string {
 	return `
 #version 430
 uniform mat4 projection;
@@ -22,13 +24,18 @@ uniform mat4 model;
 uniform float slices;
 uniform float inslice;
 uniform float curSlice;
-uniform vec3 ships[` + strconv.Itoa(numSlices) + `];
+layout(std430) buffer Ships {
+  float shipHistory[];
+};
 in vec3 vert;
 in float mass;
 out float z;
 out float scale;
 out vec3 color;
 out float gscale;
+vec3 ships(int i) {
+  return vec3(shipHistory[i*3], shipHistory[i*3+1], shipHistory[i*3+2]);
+}
 void main() {
   float c = ( mass - 0.5 )/ 128.5;
   color = clamp( vec3( 1.0 - c, 0.83 - c, c /2 + 0.5 ), 0.0, 1.0 );
@@ -38,8 +45,8 @@ void main() {
     color = vec3(0);
   }
   gscale = pow( mass, 1.0/3.0);
-  vec3 dif = (ships[int(curSlice)]-ships[int(mod((gl_VertexID-1)/2, slices))]);
-  vec3 cvert = vert - ships[int(curSlice)]+dif*0.3;
+  vec3 dif = (ships(int(curSlice))-ships(int(mod((gl_VertexID-1)/2, slices))));
+  vec3 cvert = vert - ships(int(curSlice))+dif*0.3;
   scale = mod( (gl_VertexID-1)/2-1-curSlice, slices) / slices;
   if(int(mod(gl_VertexID/2-1-curSlice,slices)) == 0){
     scale = 0;
@@ -111,9 +118,42 @@ const subs = 16
 
 const lineWidth = 8
 
+const shipsBinding = 0
+
 const forceScale = 0.0015
 const shipBrakes = 0.95
 const mouseScale = (1 / 10000.0) / forceScale
+
+// Controller 0 swaps RS with LB and the dpad with the face buttons.
+var pad0Remap = func() input.RemapTable {
+	table, err := input.ParseRemapTable(`
+pad:RS = pad:LB
+pad:LB = pad:RS
+pad:Up = pad:A
+pad:A = pad:Up
+pad:Down = pad:Y
+pad:Y = pad:Down
+pad:Right = pad:B
+pad:B = pad:Right
+pad:Left = pad:X
+pad:X = pad:Left
+`)
+	if err != nil {
+		panic(err)
+	}
+	return table
+}()
+
+// Squaring stick and trigger response gives finer control near center.
+var flightStick = input.StickDeadzone{Shape: input.DeadzoneScaledRadial,
+	Deadzone: input.Deadzone{Inner: 0.12, Outer: 0.95, Exponent: 2}}
+var flightTrigger = input.Deadzone{Inner: 0.05, Outer: 0.98, Exponent: 2}
+
+// Distance from a star at which the ship starts to rumble, and how hard
+// thrust rumbles.
+const nearStar = 0.5
+const thrustRumble = 0.4
+
 const positionScale = 90.0
 const rotationScale = 1 / 60.0
 const velScale = 0.02
@@ -127,12 +167,19 @@ type mainApp struct {
 	starMasses    []float32
 	starArray     []float32
 	starMassArray []float32
-	shipArray     []float32
+	starMesh      *engine.Mesh
 	counter       int
 	paused        bool
-	ship          BHShip
-	lastShip      BHShip
+	step          int
+	seed          int64
+	hud           hud
+	controls      controls
+	players       []*player
 	calcchan      chan int
+	// Whether losing focus paused the game, so regaining it resumes.
+	blurPaused bool
+	// Seconds left to close the window again to quit.
+	closeAsked float32
 }
 
 func project(a, b mgl32.Vec3) mgl32.Vec3 {
@@ -227,6 +274,25 @@ func (this *BHShip) control(angular, linear mgl32.Vec3) {
 	}
 	this.tick()
 }
+
+// rumble shakes gp with the thrust of ship on the high-frequency motor and, on the
+// low-frequency one, harder the closer the ship passes to a star.
+func (this *mainApp) rumble(gp *input.GamePad, ship *BHShip, thrust float32) {
+	if !gp.Active {
+		return
+	}
+	var near float32
+	// The star workers may still be writing stars; oldStars holds still.
+	for i := range this.oldStars {
+		if dist := this.oldStars[i].Sub(ship.position).Len(); dist < nearStar && 1-dist/nearStar > near {
+			near = 1 - dist/nearStar
+		}
+	}
+	if thrust > 1 {
+		thrust = 1
+	}
+	gp.SetRumble(near, thrust*thrustRumble)
+}
 func (this *mainApp) updateStarsSub(start, end int, ch chan int) {
 	for i := start; i < end; i++ {
 		for j := 0; j < numStars; j++ {
@@ -256,6 +322,18 @@ func (this *mainApp) updateStars(ch chan int) {
 		start = end
 	}
 }
+
+// waitStars waits for the workers started by the last updateStars, if they
+// have not been waited for yet.
+func (this *mainApp) waitStars() {
+	if this.calcchan == nil {
+		return
+	}
+	for i := 0; i < subs; i++ {
+		<-this.calcchan
+	}
+	this.calcchan = nil
+}
 func (this *mainApp) genLines(engine *engine.Engine) {
 	curSlice := this.counter / ticksPerSlice
 	if this.counter%ticksPerSlice == 0 {
@@ -263,7 +341,9 @@ func (this *mainApp) genLines(engine *engine.Engine) {
 			this.counter = 0
 			curSlice = 0
 		}
-		engine.UniformFloat("main", "curSlice", float32(curSlice))
+		if err := engine.UniformFloat("main", "curSlice", float32(curSlice)); err != nil {
+			panic(err)
+		}
 	}
 	for i := 0; i < numStars; i++ {
 		for j := 0; j < 3; j++ {
@@ -273,16 +353,21 @@ func (this *mainApp) genLines(engine *engine.Engine) {
 			this.starArray[index2] = this.oldStars[i][j]
 
 		}
-		index1 := ((curSlice*2 + 1) % (numSlices * 2)) + (i * 2 * numSlices)
-		index2 := ((curSlice*2 + 2) % (numSlices * 2)) + (i * 2 * numSlices)
+		index1 := ((curSlice*6 + 3) % (numSlices * 6)) + (i * 6 * numSlices)
+		index2 := ((curSlice*6 + 6) % (numSlices * 6)) + (i * 6 * numSlices)
+		this.starMesh.MarkDirty("vert", index1, index1+3)
+		this.starMesh.MarkDirty("vert", index2, index2+3)
+		index1 = ((curSlice*2 + 1) % (numSlices * 2)) + (i * 2 * numSlices)
+		index2 = ((curSlice*2 + 2) % (numSlices * 2)) + (i * 2 * numSlices)
 		this.starMassArray[index1] = this.starMasses[i]
 		this.starMassArray[index2] = this.starMasses[i]
+		this.starMesh.MarkDirty("mass", index1, index1+1)
+		this.starMesh.MarkDirty("mass", index2, index2+1)
 	}
 	this.counter++
 }
 func (this *mainApp) starInit() {
 	this.starArray = make([]float32, numStars*6*numSlices)
-	this.shipArray = make([]float32, numSlices*3)
 	this.starMassArray = make([]float32, numStars*2*numSlices)
 	this.starMasses = make([]float32, numStars)
 	this.stars = make([]mgl32.Vec3, numStars)
@@ -298,72 +383,87 @@ func (this *mainApp) starInit() {
 	}
 	this.oldStars = this.stars
 }
+func (this *mainApp) reset(engine *engine.Engine) {
+	// The workers write stars and velocities, which starInit replaces.
+	this.waitStars()
+	this.starInit()
+	this.step = 0
+	this.starMesh.MarkDirty("vert", 0, len(this.starArray))
+	this.starMesh.MarkDirty("mass", 0, len(this.starMassArray))
+	for _, p := range this.players {
+		p.resetHistory(engine)
+	}
+}
 func (this *mainApp) Init(engine *engine.Engine, input *input.Input) {
 	fmt.Println("Init start!")
-	this.ship.orientation = mgl32.QuatIdent()
-	this.ship.rotation = mgl32.QuatIdent()
 
-	gp := &input.GamePads[0]
-	gp.Swap(&gp.RS, &gp.LB)
-	gp.SwapDpad(mgl32.Vec2{0, 1}, &gp.A)
-	gp.SwapDpad(mgl32.Vec2{0, -1}, &gp.Y)
-	gp.SwapDpad(mgl32.Vec2{1, 0}, &gp.B)
-	gp.SwapDpad(mgl32.Vec2{-1, 0}, &gp.X)
+	if err := input.GamePads[0].SetRemap(pad0Remap); err != nil {
+		panic(err)
+	}
+	for i := range input.GamePads {
+		gp := &input.GamePads[i]
+		gp.SetLeftStickDeadzone(flightStick)
+		gp.SetRightStickDeadzone(flightStick)
+		gp.SetLeftTriggerDeadzone(flightTrigger)
+		gp.SetRightTriggerDeadzone(flightTrigger)
+	}
 
 	last := glfw.GetTime()
 
-	engine.MakeProgramOrPanic("main", vertexShader(numSlices), geometryShader(), fragmentShader())
+	engine.MakeProgramOrPanic("main", map[uint32]string{
+		gl.VERTEX_SHADER:   vertexShader(),
+		gl.GEOMETRY_SHADER: geometryShader(),
+		gl.FRAGMENT_SHADER: fragmentShader(),
+	})
 	engine.UseProgram("main")
 	mod := mgl32.Ident4()
-	engine.UniformMatrix("main", "model", mod)
-	engine.UniformFloat("main", "slices", float32(numSlices))
+	if err := engine.UniformMatrix("main", "model", mod); err != nil {
+		panic(err)
+	}
+	if err := engine.UniformFloat("main", "slices", float32(numSlices)); err != nil {
+		panic(err)
+	}
 	engine.FragLocation("main", "outputColor")
+	if err := engine.BindStorageBlock("main", "Ships", shipsBinding); err != nil {
+		panic(err)
+	}
 
 	engine.GrabMouse(true)
-	rand.Seed(time.Now().UnixNano())
-	this.starInit()
+	engine.SetMouseSettings(relativeMouse)
+	this.seed = time.Now().UnixNano()
+	if playback := engine.Playback(); playback != nil {
+		this.seed = playback.Seed
+	}
+	if *recordPath != "" {
+		engine.Record(this.seed)
+	}
+	rand.Seed(this.seed)
+	this.hud.init(engine)
+	this.controls.init()
+	this.players = []*player{newPlayer(0, this.controls.actions)}
+	this.starMesh = engine.MakeMesh("stars", "main", gl.LINES)
+	this.starMesh.SetUsage("vert", gl.DYNAMIC_DRAW)
+	this.starMesh.SetUsage("mass", gl.DYNAMIC_DRAW)
+	this.reset(engine)
 	fmt.Printf("Init took %v", last-glfw.GetTime())
 }
 func (this *mainApp) Tick(engine *engine.Engine, input *input.Input, delta float32) bool {
 
-	// Ship
+	// Ships
 	{
-		var accel, aaccel mgl32.Vec3
-		aaccel[2] = float32(0)
-		aaccel[0] = -input.GamePads[0].LeftStick.X() + input.Mouse.Delta.X()*mouseScale
-		aaccel[1] = input.GamePads[0].LeftStick.Y() + input.Mouse.Delta.Y()*mouseScale
-		accel[0] = input.GamePads[0].RightStick.X()
-		accel[1] = input.GamePads[0].RightStick.Y()
-		accel[2] = input.GamePads[0].LeftTrigger - input.GamePads[0].RightTrigger
-		if input.GamePads[0].LB || engine.GetKey(glfw.KeyQ) {
-			aaccel[2] += 1
+		for _, event := range input.PadEvents {
+			this.hud.notify(event.String())
 		}
-		if input.GamePads[0].RB || engine.GetKey(glfw.KeyE) {
-			aaccel[2] -= 1
+		this.controls.tick(engine, input)
+		this.players[0].actions = this.controls.actions
+		for _, p := range this.players[1:] {
+			p.actions.Update(input, engine.GetKey)
 		}
-		if engine.GetKey(glfw.KeyD) {
-			accel[0] += 1
+		this.updatePlayers(engine, input)
+		slot := ((this.counter / ticksPerSlice) % numSlices) * 3
+		for _, p := range this.players {
+			p.fly(engine, this, input, slot)
 		}
-		if engine.GetKey(glfw.KeyA) {
-			accel[0] -= 1
-		}
-		if engine.GetKey(glfw.KeyW) {
-			accel[1] += 1
-		}
-		if engine.GetKey(glfw.KeyS) {
-			accel[1] -= 1
-		}
-		if engine.GetKey(glfw.KeyLeftControl) {
-			accel[2] += 1
-		}
-		if engine.GetKey(glfw.KeyLeftShift) {
-			accel[2] -= 1
-		}
-		this.ship.control(aaccel, accel)
-		for i := 0; i < 3; i++ {
-			this.shipArray[((this.counter/ticksPerSlice)%numSlices)*3+i] = this.ship.position[i]
-		}
-
 	}
 	engine.UseProgram("main")
 	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
@@ -375,52 +475,68 @@ func (this *mainApp) Tick(engine *engine.Engine, input *input.Input, delta float
 	quit := false
 	{
 		if !this.paused {
-			if this.calcchan != nil {
-				for i := 0; i < subs; i++ {
-					<-this.calcchan
-				}
-			} else {
-				this.calcchan = make(chan int)
-			}
-
+			this.waitStars()
+			this.calcchan = make(chan int)
 			this.updateStars(this.calcchan)
+			this.step++
 			this.genLines(engine)
 		}
-		engine.SetBuffer("main", "vert", this.starArray, 3)
-		engine.SetBuffer("main", "mass", this.starMassArray, 1)
+		this.starMesh.StreamAttrib("vert", this.starArray, 3)
+		this.starMesh.StreamAttrib("mass", this.starMassArray, 1)
+	}
+	inslice := float32((this.counter-1)%ticksPerSlice) / float32(ticksPerSlice)
+	if err := engine.UniformFloat("main", "inslice", inslice); err != nil {
+		panic(err)
 	}
-	engine.UniformVecs("main", "ships", this.shipArray)
-	engine.UniformFloat("main", "inslice", float32((this.counter-1)%ticksPerSlice)/float32(ticksPerSlice))
 	modelX := mgl32.HomogRotate3D(float32(this.rotx), mgl32.Vec3{0, 1, 0})
 	modelY := mgl32.HomogRotate3D(float32(this.roty), mgl32.Vec3{0, 0, 1})
 	modelX = modelX.Mul4(modelY)
-	engine.UniformMatrix("main", "model", modelX)
-	proj := mgl32.Perspective(mgl32.DegToRad(45), engine.Height/engine.Width, 0.1, 100)
-	engine.UniformMatrix("main", "projection", proj)
-	camera := this.ship.orientation.Inverse().Mat4()
-	engine.UniformMatrix("main", "camera", camera)
-	
-	gl.DrawArrays(gl.LINES, 0, int32(numStars*2*numSlices))
-	if input.Mouse.Left && !engine.IsMouseGrabbed() {
-		engine.GrabMouse(true)
-	}
-	if engine.GetKeyPressed(glfw.KeyR) || input.GamePads[0].AP {
-		this.starInit()
+	if err := engine.UniformMatrix("main", "model", modelX); err != nil {
+		panic(err)
 	}
-	if engine.GetKeyPressed(glfw.KeyF) || input.GamePads[0].YP {
-		this.ship.setMode((this.ship.mode + 1) % 3)
+
+	this.drawViews(engine)
+	this.hud.tick(delta)
+	if this.closeAsked > 0 {
+		this.closeAsked -= delta
 	}
-	if engine.GetKeyPressed(glfw.KeySpace) || input.GamePads[0].BP {
-		this.paused = !this.paused
+	this.hud.draw(engine, this, input)
+	this.controls.draw(engine)
+	drawReticle(engine, &input.Mouse)
+	if input.Mouse.Left && !engine.IsMouseGrabbed() {
+		engine.GrabMouse(true)
 	}
-	if engine.GetKeyPressed(glfw.KeyEscape) {
-		if engine.IsMouseGrabbed() {
-			engine.GrabMouse(false)
-		} else {
-			quit = true
+	actions := this.controls.actions
+	if !this.controls.menu {
+		if actions.Pressed("reset") {
+			this.reset(engine)
+		}
+		if actions.Pressed("debug") {
+			this.hud.debug = !this.hud.debug
+		}
+		if actions.Pressed("pause") {
+			this.paused = !this.paused
+			this.blurPaused = false
+		}
+		if actions.Pressed("mouse_mode") {
+			toggleMouseMode(engine)
+		}
+		// The engine toggles on Alt+Enter itself.
+		alt := engine.GetKey(glfw.KeyLeftAlt) || engine.GetKey(glfw.KeyRightAlt)
+		if actions.Pressed("fullscreen") && !alt {
+			if err := engine.ToggleFullscreen(); err != nil {
+				fmt.Println(err)
+			}
+		}
+		if actions.Pressed("escape") {
+			if engine.IsMouseGrabbed() {
+				engine.GrabMouse(false)
+			} else {
+				quit = true
+			}
 		}
 	}
-	if quit || input.GamePads[0].Select {
+	if quit || actions.Held("quit") {
 		return false
 	} else {
 		return true
@@ -428,15 +544,86 @@ func (this *mainApp) Tick(engine *engine.Engine, input *input.Input, delta float
 }
 func (this *mainApp) Quit(engine *engine.Engine) {
 	fmt.Println("Quit!")
+	if recording := engine.StopRecording(); recording != nil {
+		if err := recording.Save(*recordPath); err != nil {
+			fmt.Printf("Could not save recording: %v\n", err)
+		}
+	}
+}
 
+// OnBlur pauses the game while the window is in the background.
+func (this *mainApp) OnBlur(engine *engine.Engine) {
+	if !this.paused {
+		this.paused = true
+		this.blurPaused = true
+	}
+}
+func (this *mainApp) OnFocus(engine *engine.Engine) {
+	if this.blurPaused {
+		this.paused = false
+		this.blurPaused = false
+	}
+}
+
+// OnCloseRequest asks for the window to be closed twice, within closeTime
+// seconds, before quitting.
+func (this *mainApp) OnCloseRequest(engine *engine.Engine) bool {
+	if this.closeAsked > 0 {
+		return true
+	}
+	this.closeAsked = closeTime
+	this.hud.notify("Close again to quit")
+	return false
+}
+
+const closeTime = 3
+
+// OnFileDrop loads bindings from dropped text files, and saves them.
+func (this *mainApp) OnFileDrop(engine *engine.Engine, paths []string) {
+	for _, path := range paths {
+		if !strings.HasSuffix(path, ".txt") {
+			this.hud.notify("Not a bindings file: " + filepath.Base(path))
+			continue
+		}
+		err := this.controls.actions.Load(path)
+		this.controls.clampSelected()
+		if err != nil {
+			this.hud.notify(fmt.Sprintf("Could not load %s: %v", filepath.Base(path), err))
+			continue
+		}
+		this.controls.save()
+		this.hud.notify("Loaded bindings from " + filepath.Base(path))
+	}
+}
+
+var recordPath = flag.String("record", "", "record input to `file`")
+var playPath = flag.String("play", "", "replay input recorded with -record from `file`")
+var vsync = flag.String("vsync", "on", "vertical sync: off, on or adaptive")
+var maxFPS = flag.Float64("fps", 0, "cap the frame rate at `fps` frames per second")
+
+var vsyncModes = map[string]engine.VSync{
+	"off": engine.VSyncOff, "on": engine.VSyncOn, "adaptive": engine.VSyncAdaptive,
 }
 
 func main() {
+	flag.Parse()
 	fmt.Println("start!")
+	var recording *engine.Recording
+	if *playPath != "" {
+		var err error
+		if recording, err = engine.LoadRecording(*playPath); err != nil {
+			panic(err)
+		}
+	}
+	mode, ok := vsyncModes[*vsync]
+	if !ok {
+		panic(fmt.Errorf("unknown vsync mode %s", *vsync))
+	}
 	var m mainApp
-	engine := engine.Engine{App: &m, Width: 1024 * 4.0 / 3.0, Height: 1024, Title: "Intergallactic Cheese!!!"}
+	engine := engine.Engine{App: &m, Width: 1024 * 4.0 / 3.0, Height: 1024, Title: "Intergallactic Cheese!!!",
+		VSync: mode, MaxFPS: float32(*maxFPS)}
+	engine.Play(recording)
 
 	for engine.Tick() {
-		// lol time.Sleep(10000000)
 	}
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"./engine"
+	"./input"
+	"fmt"
+	"github.com/go-gl/gl/v4.3-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+const maxPlayers = 4
+
+// A player flies one ship, seen from their own region of the window. The
+// first player uses the keyboard, mouse and controller 0; others join by
+// pressing Start on another controller and leave with Select. A player whose
+// controller disconnects keeps their ship until it comes back.
+type player struct {
+	pad     int
+	ship    BHShip
+	actions *input.Actions
+	// Where the ship has been, one position per slice, for the shader.
+	history []float32
+}
+
+func newPlayer(pad int, actions *input.Actions) *player {
+	p := &player{pad: pad, actions: actions}
+	p.ship.orientation = mgl32.QuatIdent()
+	p.ship.rotation = mgl32.QuatIdent()
+	p.history = make([]float32, numSlices*3)
+	return p
+}
+
+// buffer names the storage buffer holding the player's history.
+func (this *player) buffer() string {
+	return fmt.Sprintf("ships.%d", this.pad)
+}
+
+// fly steers the ship by the player's actions and records its position in
+// slot of its history.
+func (this *player) fly(engine *engine.Engine, app *mainApp, in *input.Input, slot int) {
+	var accel, aaccel mgl32.Vec3
+	if !app.controls.menu {
+		aaccel[0] = this.actions.Value("yaw")
+		aaccel[1] = this.actions.Value("pitch")
+		aaccel[2] = this.actions.Value("roll")
+		accel[0] = this.actions.Value("thrust_right")
+		accel[1] = this.actions.Value("thrust_up")
+		accel[2] = -this.actions.Value("thrust_forward")
+		if this.actions.Pressed("mode") {
+			this.ship.setMode((this.ship.mode + 1) % 3)
+		}
+	}
+	this.ship.control(aaccel, accel)
+	app.rumble(&in.GamePads[this.pad], &this.ship, accel.Len())
+	for i := 0; i < 3; i++ {
+		this.history[slot+i] = this.ship.position[i]
+	}
+	engine.UpdateStorageBuffer(this.buffer(), slot, this.history[slot:slot+3])
+}
+
+// resetHistory forgets where the ship has been.
+func (this *player) resetHistory(engine *engine.Engine) {
+	this.history = make([]float32, numSlices*3)
+	engine.SetStorageBuffer(this.buffer(), shipsBinding, this.history)
+}
+
+// padActions copies the controller bindings of the first player's actions
+// for a player on pad.
+func (this *controls) padActions(pad int) *input.Actions {
+	actions := input.NewActions()
+	actions.Pad = pad
+	for _, name := range this.actions.Names() {
+		var sources []input.Source
+		for _, source := range this.actions.Bindings(name) {
+			if source.Kind == input.SourcePadButton || source.Kind == input.SourcePadAxis {
+				sources = append(sources, source)
+			}
+		}
+		actions.Bind(name, sources...)
+	}
+	return actions
+}
+
+// updatePlayers adds a player for each free controller whose Start was just
+// pressed and removes joined players who pressed Select.
+func (this *mainApp) updatePlayers(engine *engine.Engine, in *input.Input) {
+	kept := this.players[:1]
+	for _, p := range this.players[1:] {
+		if !p.actions.Pressed("quit") {
+			kept = append(kept, p)
+		} else {
+			in.GamePads[p.pad].StopRumble()
+		}
+	}
+	this.players = kept
+	for pad := 1; pad < len(in.GamePads) && len(this.players) < maxPlayers; pad++ {
+		if !in.GamePads[pad].Pressed(input.ButtonStart) || this.player(pad) != nil {
+			continue
+		}
+		p := newPlayer(pad, this.controls.padActions(pad))
+		p.actions.Update(in, engine.GetKey)
+		p.resetHistory(engine)
+		this.players = append(this.players, p)
+	}
+}
+
+// player is the player on pad, or nil.
+func (this *mainApp) player(pad int) *player {
+	for _, p := range this.players {
+		if p.pad == pad {
+			return p
+		}
+	}
+	return nil
+}
+
+// regions splits a window width by height pixels between n players: all of
+// it for one, side by side halves for two and quarters for more. Regions are
+// x, y, width, height with y up, as gl.Viewport takes them.
+func regions(n, width, height int) [][4]int32 {
+	w, h := int32(width), int32(height)
+	switch n {
+	case 1:
+		return [][4]int32{{0, 0, w, h}}
+	case 2:
+		return [][4]int32{{0, 0, w / 2, h}, {w / 2, 0, w - w/2, h}}
+	default:
+		all := [][4]int32{
+			{0, h / 2, w / 2, h - h/2}, {w / 2, h / 2, w - w/2, h - h/2},
+			{0, 0, w / 2, h / 2}, {w / 2, 0, w - w/2, h / 2},
+		}
+		return all[:n]
+	}
+}
+
+// drawViews draws the galaxy once per player, from their ship into their
+// region, then restores the whole window.
+func (this *mainApp) drawViews(engine *engine.Engine) {
+	width, height := engine.Viewport()
+	for i, region := range regions(len(this.players), width, height) {
+		p := this.players[i]
+		gl.Viewport(region[0], region[1], region[2], region[3])
+		proj := engine.Perspective(mgl32.DegToRad(45), int(region[2]), int(region[3]), 0.1, 100)
+		if err := engine.UniformMatrix("main", "projection", proj); err != nil {
+			panic(err)
+		}
+		if err := engine.UniformMatrix("main", "camera", p.ship.orientation.Inverse().Mat4()); err != nil {
+			panic(err)
+		}
+		if err := engine.AttachBlockBuffer(p.buffer()); err != nil {
+			panic(err)
+		}
+		this.starMesh.Draw()
+	}
+	engine.BindDefaultFramebuffer()
+}